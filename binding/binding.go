@@ -0,0 +1,122 @@
+package binding
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+)
+
+const (
+	MIMEJSON              = "application/json"
+	MIMEXML               = "application/xml"
+	MIMEXML2              = "text/xml"
+	MIMEPOSTForm          = "application/x-www-form-urlencoded"
+	MIMEMultipartPOSTForm = "multipart/form-data"
+	MIMEYAML              = "application/yaml"
+	MIMEYAML2             = "application/x-yaml"
+	MIMETOML              = "application/toml"
+	MIMEMsgPack           = "application/msgpack"
+	MIMEMsgPack2          = "application/x-msgpack"
+	MIMEProtoBuf          = "application/protobuf"
+	MIMEProtoBuf2         = "application/x-protobuf"
+)
+
+// defaultMemory is the maximum number of bytes of a multipart body kept in
+// memory, the rest is stored in temporary files. Context.BindWith parses
+// the body beforehand with Server.MaxMultipartMemory instead.
+const defaultMemory = 32 << 20 // 32MB
+
+// Binding decodes the request into obj, which must be a pointer.
+type Binding interface {
+	Name() string
+	Bind(req *http.Request, obj interface{}) error
+}
+
+// URIBinding decodes the path parameters into obj, which must be a pointer
+// to a struct.
+type URIBinding interface {
+	Name() string
+	BindURI(params map[string][]string, obj interface{}) error
+}
+
+var (
+	JSON  Binding    = jsonBinding{}
+	XML   Binding    = xmlBinding{}
+	Form  Binding    = formBinding{}
+	Query Binding    = queryBinding{}
+	URI   URIBinding = uriBinding{}
+)
+
+// ErrEmptyBody is returned by the body bindings when the request has no body.
+var ErrEmptyBody = errors.New("binding: request body is empty")
+
+// Default returns the binding for the request method and content type,
+// looked up in the bindings registered by MIME type. Requests without body,
+// form bodies and unknown content types are bound with Form.
+func Default(method, contentType string) Binding {
+	if method == http.MethodGet || method == http.MethodHead {
+		return Form
+	}
+	if b, ok := Lookup(contentType); ok {
+		return b
+	}
+	return Form
+}
+
+type jsonBinding struct{}
+
+func (jsonBinding) Name() string { return "json" }
+
+func (jsonBinding) Bind(req *http.Request, obj interface{}) error {
+	if req == nil || req.Body == nil {
+		return ErrEmptyBody
+	}
+	if err := json.NewDecoder(req.Body).Decode(obj); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return Errors{{Field: typeErr.Field, Value: typeErr.Value, Err: err}}
+		}
+		return err
+	}
+	return nil
+}
+
+type xmlBinding struct{}
+
+func (xmlBinding) Name() string { return "xml" }
+
+func (xmlBinding) Bind(req *http.Request, obj interface{}) error {
+	if req == nil || req.Body == nil {
+		return ErrEmptyBody
+	}
+	return xml.NewDecoder(req.Body).Decode(obj)
+}
+
+type formBinding struct{}
+
+func (formBinding) Name() string { return "form" }
+
+// Bind decodes the query string and the urlencoded or multipart body.
+func (formBinding) Bind(req *http.Request, obj interface{}) error {
+	if err := req.ParseMultipartForm(defaultMemory); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return err
+	}
+	return mapForm(obj, req.Form, "form")
+}
+
+type queryBinding struct{}
+
+func (queryBinding) Name() string { return "query" }
+
+func (queryBinding) Bind(req *http.Request, obj interface{}) error {
+	return mapForm(obj, req.URL.Query(), "form")
+}
+
+type uriBinding struct{}
+
+func (uriBinding) Name() string { return "uri" }
+
+func (uriBinding) BindURI(params map[string][]string, obj interface{}) error {
+	return mapForm(obj, params, "uri")
+}
@@ -0,0 +1,155 @@
+package binding
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type address struct {
+	City string `form:"city" json:"city"`
+	Zip  *int   `form:"zip" json:"zip"`
+}
+
+type user struct {
+	Name     string        `form:"name" json:"name" xml:"name" uri:"name"`
+	Age      int           `form:"age" json:"age" xml:"age" uri:"age"`
+	Admin    bool          `form:"admin"`
+	Tags     []string      `form:"tag"`
+	Scores   []float64     `form:"score"`
+	Birthday time.Time     `form:"birthday" time_format:"2006-01-02" time_utc:"1"`
+	Created  time.Time     `form:"created" time_format:"unix"`
+	Timeout  time.Duration `form:"timeout"`
+	Ignored  string        `form:"-"`
+	Address  address
+}
+
+func TestDefault(t *testing.T) {
+	assert.Equal(t, Form, Default(http.MethodGet, MIMEJSON))
+	assert.Equal(t, JSON, Default(http.MethodPost, MIMEJSON))
+	assert.Equal(t, XML, Default(http.MethodPut, MIMEXML))
+	assert.Equal(t, XML, Default(http.MethodPut, MIMEXML2))
+	assert.Equal(t, Form, Default(http.MethodPost, MIMEPOSTForm))
+	assert.Equal(t, Form, Default(http.MethodPost, MIMEMultipartPOSTForm))
+}
+
+func TestQueryBinding(t *testing.T) {
+	q := url.Values{
+		"name":     {"bob"},
+		"age":      {"32"},
+		"admin":    {"true"},
+		"tag":      {"a", "b"},
+		"score":    {"1.5", "2"},
+		"birthday": {"1990-02-03"},
+		"created":  {"1500000000"},
+		"timeout":  {"1m30s"},
+		"Ignored":  {"x"},
+		"-":        {"x"},
+		"city":     {"paris"},
+		"zip":      {"75001"},
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/?"+q.Encode(), nil)
+
+	var u user
+	assert.NoError(t, Query.Bind(req, &u))
+	assert.Equal(t, "bob", u.Name)
+	assert.Equal(t, 32, u.Age)
+	assert.True(t, u.Admin)
+	assert.Equal(t, []string{"a", "b"}, u.Tags)
+	assert.Equal(t, []float64{1.5, 2}, u.Scores)
+	assert.Equal(t, time.Date(1990, 2, 3, 0, 0, 0, 0, time.UTC), u.Birthday)
+	assert.Equal(t, int64(1500000000), u.Created.Unix())
+	assert.Equal(t, 90*time.Second, u.Timeout)
+	assert.Empty(t, u.Ignored)
+	assert.Equal(t, "paris", u.Address.City)
+	assert.Equal(t, 75001, *u.Address.Zip)
+}
+
+func TestFormBindingErrors(t *testing.T) {
+	body := url.Values{"name": {"bob"}, "age": {"old"}, "zip": {"abc"}}.Encode()
+	req, _ := http.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", MIMEPOSTForm)
+
+	var u user
+	err := Form.Bind(req, &u)
+	errs, ok := err.(Errors)
+	assert.True(t, ok)
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "Age", errs[0].Field)
+	assert.Equal(t, "old", errs[0].Value)
+	assert.Equal(t, "Address.Zip", errs[1].Field)
+	assert.Equal(t, "abc", errs[1].Value)
+	assert.Equal(t, "bob", u.Name)
+}
+
+type linkedNode struct {
+	Name string `form:"name"`
+	Next *linkedNode
+}
+
+type profile struct {
+	Name    string `form:"name"`
+	Address *struct {
+		City string `form:"city" binding:"required"`
+	}
+}
+
+func TestNestedStructPointers(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/?name=a", nil)
+	var n linkedNode
+	assert.NoError(t, Query.Bind(req, &n))
+	assert.Equal(t, linkedNode{Name: "a"}, n)
+
+	// the pointer is left nil when the form has none of its fields.
+	var p profile
+	assert.NoError(t, Query.Bind(req, &p))
+	assert.Nil(t, p.Address)
+	assert.NoError(t, NewValidator().Validate(&p))
+	var required struct {
+		Address *address `binding:"required"`
+	}
+	assert.NoError(t, Query.Bind(req, &required))
+	assert.Error(t, NewValidator().Validate(&required))
+
+	req, _ = http.NewRequest(http.MethodGet, "/?name=a&city=paris", nil)
+	assert.NoError(t, Query.Bind(req, &p))
+	assert.Equal(t, "paris", p.Address.City)
+}
+
+func TestURIBinding(t *testing.T) {
+	var u user
+	assert.NoError(t, URI.BindURI(map[string][]string{"name": {"bob"}, "age": {"3"}}, &u))
+	assert.Equal(t, "bob", u.Name)
+	assert.Equal(t, 3, u.Age)
+
+	assert.Equal(t, errNotStructPointer, URI.BindURI(nil, u))
+}
+
+func TestJSONBinding(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"name":"bob","age":32}`))
+	var u user
+	assert.NoError(t, JSON.Bind(req, &u))
+	assert.Equal(t, "bob", u.Name)
+	assert.Equal(t, 32, u.Age)
+
+	req, _ = http.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"name":"bob","Address":{"city":1}}`))
+	err := JSON.Bind(req, &u)
+	errs, ok := err.(Errors)
+	assert.True(t, ok)
+	assert.Equal(t, "Address.city", errs[0].Field)
+
+	req, _ = http.NewRequest(http.MethodPost, "/", nil)
+	assert.Equal(t, ErrEmptyBody, JSON.Bind(req, &u))
+}
+
+func TestXMLBinding(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`<user><name>bob</name><age>32</age></user>`))
+	var u user
+	assert.NoError(t, XML.Bind(req, &u))
+	assert.Equal(t, "bob", u.Name)
+	assert.Equal(t, 32, u.Age)
+}
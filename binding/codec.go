@@ -0,0 +1,69 @@
+package binding
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// bindings holds the bindings by MIME type. The codec packages, e.g.
+// github.com/chen-zyc/gweb/codec/yaml, register more formats when imported.
+var (
+	bindingsMu sync.RWMutex
+	bindings   = map[string]Binding{
+		MIMEJSON: JSON,
+		MIMEXML:  XML,
+		MIMEXML2: XML,
+	}
+)
+
+// Register sets the binding used by Default for the request bodies of the
+// MIME type, given without parameters.
+func Register(mime string, b Binding) {
+	bindingsMu.Lock()
+	bindings[mime] = b
+	bindingsMu.Unlock()
+}
+
+// Lookup returns the binding registered for the MIME type.
+func Lookup(mime string) (b Binding, ok bool) {
+	bindingsMu.RLock()
+	b, ok = bindings[mime]
+	bindingsMu.RUnlock()
+	return
+}
+
+// MIMETypes returns the sorted MIME types having a binding.
+func MIMETypes() []string {
+	bindingsMu.RLock()
+	types := make([]string, 0, len(bindings))
+	for mime := range bindings {
+		types = append(types, mime)
+	}
+	bindingsMu.RUnlock()
+	sort.Strings(types)
+	return types
+}
+
+// ErrNoBinding is returned by the binding of MIMEBinding when no binding is
+// registered for the MIME type.
+var ErrNoBinding = errors.New("binding: no binding registered")
+
+// MIMEBinding returns the binding registered for the MIME type, or a
+// binding failing with ErrNoBinding if there is none.
+func MIMEBinding(mime string) Binding {
+	if b, ok := Lookup(mime); ok {
+		return b
+	}
+	return missingBinding(mime)
+}
+
+type missingBinding string
+
+func (b missingBinding) Name() string { return string(b) }
+
+func (b missingBinding) Bind(*http.Request, interface{}) error {
+	return fmt.Errorf("%w for %s", ErrNoBinding, string(b))
+}
@@ -0,0 +1,33 @@
+package binding
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestDefaultRegistry(t *testing.T) {
+	assert.Equal(t, JSON, Default(http.MethodPost, MIMEJSON))
+	assert.Equal(t, XML, Default(http.MethodPost, MIMEXML2))
+	assert.Equal(t, Form, Default(http.MethodPost, MIMEYAML))
+	assert.Equal(t, Form, Default(http.MethodPost, "application/vnd.custom"))
+
+	custom := jsonBinding{}
+	Register("application/vnd.custom", custom)
+	defer func() {
+		bindingsMu.Lock()
+		delete(bindings, "application/vnd.custom")
+		bindingsMu.Unlock()
+	}()
+	assert.Equal(t, custom, Default(http.MethodPost, "application/vnd.custom"))
+	assert.Contains(t, MIMETypes(), "application/vnd.custom")
+	assert.Equal(t, custom, MIMEBinding("application/vnd.custom"))
+}
+
+func TestMIMEBinding(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "/", nil)
+	err := MIMEBinding(MIMEYAML).Bind(req, &struct{}{})
+	assert.True(t, errors.Is(err, ErrNoBinding))
+	assert.EqualError(t, err, "binding: no binding registered for application/yaml")
+}
@@ -0,0 +1,67 @@
+package binding
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// FieldError describes why a single field could not be bound or validated.
+type FieldError struct {
+	// Field is the path of the struct field, e.g. "Address.City".
+	Field string
+	// Tag and Param are the validation rule which failed, e.g. "min" and "3".
+	// They are empty if the value could not be converted.
+	Tag   string
+	Param string
+	// Value is the input which could not be converted or validated.
+	Value string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("field '%s': %v", e.Field, e.Err)
+	}
+	return fmt.Sprintf("field '%s' with value '%s': %v", e.Field, e.Value, e.Err)
+}
+
+func (e *FieldError) Unwrap() error { return e.Err }
+
+func (e *FieldError) MarshalJSON() ([]byte, error) {
+	var msg string
+	if e.Err != nil {
+		msg = e.Err.Error()
+	}
+	return json.Marshal(struct {
+		Field   string `json:"field"`
+		Tag     string `json:"tag,omitempty"`
+		Param   string `json:"param,omitempty"`
+		Value   string `json:"value,omitempty"`
+		Message string `json:"message"`
+	}{e.Field, e.Tag, e.Param, e.Value, msg})
+}
+
+// Errors collects the errors of all fields which could not be bound or
+// validated.
+type Errors []*FieldError
+
+func (es Errors) Error() string {
+	msgs := make([]string, len(es))
+	for i, e := range es {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Render writes the errors as a 400 Bad Request JSON response:
+//
+//	{"errors":[{"field":"Name","tag":"min","param":"3","value":"ab","message":"failed on the 'min=3' rule"}]}
+//
+// It implements render.Render.
+func (es Errors) Render(w http.ResponseWriter) error {
+	w.Header()["Content-Type"] = []string{"application/json; charset=utf-8"}
+	w.WriteHeader(http.StatusBadRequest)
+	return json.NewEncoder(w).Encode(map[string]Errors{"errors": es})
+}
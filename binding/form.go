@@ -0,0 +1,276 @@
+package binding
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errNotStructPointer = errors.New("binding: obj must be a non-nil pointer to a struct")
+	errUnsupportedType  = errors.New("unsupported type")
+
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// mapForm sets the fields of the struct pointed to by obj from form.
+// The key of a field is read from the tag, the field name is used if the tag
+// is empty and the field is skipped if the tag is "-". Nested structs without
+// a tag are mapped from the same form. A nil pointer to a nested struct is
+// only allocated if the form has a key for one of its fields, and a struct
+// nested in itself, e.g. a linked list, is not mapped again.
+//
+// Time fields are parsed with the layout given in the `time_format` tag,
+// RFC3339 by default, or as seconds since the epoch with "unix". The
+// `time_utc` and `time_location` tags set the location of the time.
+//
+// All conversion errors are collected and returned as Errors.
+func mapForm(obj interface{}, form map[string][]string, tag string) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errNotStructPointer
+	}
+	var errs Errors
+	mapStruct(v.Elem(), "", form, tag, nil, &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// mapStruct maps the fields of v and reports whether the form has a key for
+// one of them. parents are the types of the structs v is nested in.
+func mapStruct(v reflect.Value, namespace string, form map[string][]string, tag string, parents []reflect.Type, errs *Errors) (found bool) {
+	t := v.Type()
+	parents = append(parents, t)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" { // unexported
+			continue
+		}
+		name := sf.Tag.Get(tag)
+		if name == "-" {
+			continue
+		}
+		fieldPath := sf.Name
+		if namespace != "" {
+			fieldPath = namespace + "." + sf.Name
+		}
+
+		fv := v.Field(i)
+		if name == "" && isNestedStruct(sf.Type) {
+			if sf.Anonymous {
+				fieldPath = namespace
+			}
+			if sf.Type.Kind() != reflect.Ptr {
+				found = mapStruct(fv, fieldPath, form, tag, parents, errs) || found
+				continue
+			}
+			if isParent(parents, sf.Type.Elem()) {
+				continue
+			}
+			ptr := fv
+			if fv.IsNil() {
+				ptr = reflect.New(sf.Type.Elem())
+			}
+			if mapStruct(ptr.Elem(), fieldPath, form, tag, parents, errs) {
+				fv.Set(ptr)
+				found = true
+			}
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		vals, ok := form[name]
+		if !ok {
+			continue
+		}
+		found = true
+		if err := setField(fv, sf, vals); err != nil {
+			fe := &FieldError{Field: fieldPath, Err: err}
+			if len(vals) > 0 {
+				fe.Value = strings.Join(vals, ",")
+			}
+			*errs = append(*errs, fe)
+		}
+	}
+	return found
+}
+
+func isParent(parents []reflect.Type, t reflect.Type) bool {
+	for _, p := range parents {
+		if p == t {
+			return true
+		}
+	}
+	return false
+}
+
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != timeType && !reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+func setField(fv reflect.Value, sf reflect.StructField, vals []string) error {
+	switch fv.Kind() {
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() == reflect.Uint8 { // []byte
+			return setValue(fv, sf, firstValue(vals))
+		}
+		slice := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setValue(slice.Index(i), sf, val); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	case reflect.Array:
+		if len(vals) != fv.Len() {
+			return fmt.Errorf("%d values for an array of length %d", len(vals), fv.Len())
+		}
+		for i, val := range vals {
+			if err := setValue(fv.Index(i), sf, val); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return setValue(fv, sf, firstValue(vals))
+	}
+}
+
+func firstValue(vals []string) string {
+	if len(vals) > 0 {
+		return vals[0]
+	}
+	return ""
+}
+
+func setValue(fv reflect.Value, sf reflect.StructField, val string) error {
+	if fv.Kind() == reflect.Ptr {
+		if val == "" {
+			return nil
+		}
+		ptr := reflect.New(fv.Type().Elem())
+		if err := setValue(ptr.Elem(), sf, val); err != nil {
+			return err
+		}
+		fv.Set(ptr)
+		return nil
+	}
+
+	switch fv.Type() {
+	case timeType:
+		return setTime(fv, sf, val)
+	case durationType:
+		if val == "" {
+			val = "0"
+		}
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	if fv.CanAddr() && fv.Addr().Type().Implements(textUnmarshalerType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Bool:
+		if val == "" {
+			val = "false"
+		}
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if val == "" {
+			val = "0"
+		}
+		n, err := strconv.ParseInt(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if val == "" {
+			val = "0"
+		}
+		n, err := strconv.ParseUint(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if val == "" {
+			val = "0"
+		}
+		f, err := strconv.ParseFloat(val, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Slice: // []byte
+		fv.SetBytes([]byte(val))
+	default:
+		return errUnsupportedType
+	}
+	return nil
+}
+
+func setTime(fv reflect.Value, sf reflect.StructField, val string) error {
+	if val == "" {
+		fv.Set(reflect.ValueOf(time.Time{}))
+		return nil
+	}
+
+	layout := sf.Tag.Get("time_format")
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	if layout == "unix" {
+		sec, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(time.Unix(sec, 0)))
+		return nil
+	}
+
+	loc := time.Local
+	if isUTC, _ := strconv.ParseBool(sf.Tag.Get("time_utc")); isUTC {
+		loc = time.UTC
+	}
+	if locTag := sf.Tag.Get("time_location"); locTag != "" {
+		l, err := time.LoadLocation(locTag)
+		if err != nil {
+			return err
+		}
+		loc = l
+	}
+
+	t, err := time.ParseInLocation(layout, val, loc)
+	if err != nil {
+		return err
+	}
+	fv.Set(reflect.ValueOf(t))
+	return nil
+}
@@ -0,0 +1,458 @@
+package binding
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidatorFunc reports whether value satisfies the rule, param is the text
+// after '=' in the rule, e.g. "3" for "min=3".
+type ValidatorFunc func(value reflect.Value, param string) bool
+
+// Validator checks struct fields against the rules in their `binding` tag,
+// e.g.
+//
+//	Name  string   `binding:"required,min=2,max=32"`
+//	Email string   `binding:"omitempty,email"`
+//	Role  string   `binding:"oneof=admin user"`
+//	Code  string   `binding:"regex=^[A-Z]{3}-[0-9]+$"`
+//	Tags  []string `binding:"max=5"`
+//
+// Rules are separated by ',' and run in order. regex consumes the rest of the
+// tag, so it must be the last rule. With omitempty the rules are skipped for
+// zero values. For strings min, max and len count runes, for slices and maps
+// they check the length and for numbers the value.
+//
+// Nested structs, pointers to structs and slices or maps of structs are
+// validated recursively.
+//
+// The tags of a struct type are parsed the first time a value of the type is
+// validated, an unknown rule or a malformed parameter is reported as a
+// *TagError.
+type Validator struct {
+	mu    sync.RWMutex
+	funcs map[string]ValidatorFunc
+	// checks validate the parameters of the built-in rules which have not
+	// been replaced.
+	checks map[string]paramCheck
+	types  map[reflect.Type]*structRules
+}
+
+// paramCheck returns an error if param can not be used by a rule for fields
+// of type t.
+type paramCheck func(t reflect.Type, param string) error
+
+// TagError reports a malformed `binding` tag.
+type TagError struct {
+	Type  reflect.Type
+	Field string
+	Rule  string
+	Err   error
+}
+
+func (e *TagError) Error() string {
+	return fmt.Sprintf("binding: invalid rule '%s' of field %s.%s: %v", e.Rule, e.Type, e.Field, e.Err)
+}
+
+func (e *TagError) Unwrap() error { return e.Err }
+
+// structRules are the parsed tags of a struct type.
+type structRules struct {
+	fields []fieldRules
+	err    *TagError
+}
+
+type fieldRules struct {
+	index int
+	name  string
+	rules []rule
+}
+
+type rule struct {
+	text  string // as written in the tag, e.g. "min=3"
+	name  string
+	param string
+	fn    ValidatorFunc
+}
+
+// NewValidator returns a Validator with the built-in rules: required, min,
+// max, len, regex, email and oneof.
+func NewValidator() *Validator {
+	v := &Validator{
+		funcs: map[string]ValidatorFunc{
+			"required": hasValue,
+			"min":      isMin,
+			"max":      isMax,
+			"len":      isLen,
+			"email":    isEmail,
+			"oneof":    isOneOf,
+			"regex":    matchRegex,
+		},
+		checks: map[string]paramCheck{
+			"min":   checkCompareParam,
+			"max":   checkCompareParam,
+			"len":   checkCompareParam,
+			"regex": checkRegexParam,
+		},
+		types: make(map[reflect.Type]*structRules),
+	}
+	return v
+}
+
+// Register adds a rule, replacing the existing one with the same tag.
+func (v *Validator) Register(tag string, fn ValidatorFunc) {
+	v.mu.Lock()
+	v.funcs[tag] = fn
+	delete(v.checks, tag)
+	// the rules already parsed may use the replaced one.
+	v.types = make(map[reflect.Type]*structRules)
+	v.mu.Unlock()
+}
+
+// Validate checks the struct pointed to by obj. It returns Errors holding
+// the first failed rule of every invalid field, or a *TagError if the tags
+// of a struct type are malformed. Values other than structs are not
+// validated.
+func (v *Validator) Validate(obj interface{}) error {
+	rv := reflect.ValueOf(obj)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs Errors
+	if err := v.validateStruct(rv, "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (v *Validator) validateStruct(rv reflect.Value, namespace string, errs *Errors) *TagError {
+	sr := v.rulesOf(rv.Type())
+	if sr.err != nil {
+		return sr.err
+	}
+	for _, f := range sr.fields {
+		fieldPath := f.name
+		if namespace != "" {
+			fieldPath = namespace + "." + f.name
+		}
+		fv := rv.Field(f.index)
+
+		if fe := validateField(fv, f.rules); fe != nil {
+			fe.Field = fieldPath
+			*errs = append(*errs, fe)
+			continue
+		}
+		if err := v.dive(fv, fieldPath, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rulesOf returns the parsed rules of the struct type t.
+func (v *Validator) rulesOf(t reflect.Type) *structRules {
+	v.mu.RLock()
+	sr := v.types[t]
+	v.mu.RUnlock()
+	if sr != nil {
+		return sr
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	sr = &structRules{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" { // unexported
+			continue
+		}
+		f := fieldRules{index: i, name: sf.Name}
+		if tag := sf.Tag.Get("binding"); tag != "" && tag != "-" {
+			rules, err := v.parseTag(sf.Type, tag)
+			if err != nil {
+				err.Type, err.Field = t, sf.Name
+				sr.err = err
+				break
+			}
+			f.rules = rules
+		}
+		sr.fields = append(sr.fields, f)
+	}
+	v.types[t] = sr
+	return sr
+}
+
+// parseTag parses the rules of a field of type t. v.mu must be held.
+func (v *Validator) parseTag(t reflect.Type, tag string) ([]rule, *TagError) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var rules []rule
+	for tag != "" {
+		var text string
+		if strings.HasPrefix(tag, "regex=") {
+			text, tag = tag, ""
+		} else if i := strings.IndexByte(tag, ','); i >= 0 {
+			text, tag = tag[:i], tag[i+1:]
+		} else {
+			text, tag = tag, ""
+		}
+
+		r := rule{text: text, name: text}
+		if i := strings.IndexByte(text, '='); i >= 0 {
+			r.name, r.param = text[:i], text[i+1:]
+		}
+		if r.name != "omitempty" {
+			if r.fn = v.funcs[r.name]; r.fn == nil {
+				return nil, &TagError{Rule: text, Err: fmt.Errorf("undefined validation rule '%s'", r.name)}
+			}
+			if check := v.checks[r.name]; check != nil {
+				if err := check(t, r.param); err != nil {
+					return nil, &TagError{Rule: text, Err: err}
+				}
+			}
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// dive validates the structs held by the field.
+func (v *Validator) dive(fv reflect.Value, fieldPath string, errs *Errors) *TagError {
+	for fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface {
+		if fv.IsNil() {
+			return nil
+		}
+		fv = fv.Elem()
+	}
+
+	switch fv.Kind() {
+	case reflect.Struct:
+		if fv.Type() != timeType {
+			return v.validateStruct(fv, fieldPath, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < fv.Len(); i++ {
+			if err := v.dive(fv.Index(i), fmt.Sprintf("%s[%d]", fieldPath, i), errs); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := fv.MapRange()
+		for iter.Next() {
+			if err := v.dive(iter.Value(), fmt.Sprintf("%s[%v]", fieldPath, iter.Key()), errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateField(fv reflect.Value, rules []rule) *FieldError {
+	for _, r := range rules {
+		if r.name == "omitempty" {
+			if !hasValue(fv, "") {
+				return nil
+			}
+			continue
+		}
+
+		// only required applies to nil pointers, the other rules check the
+		// value pointed to.
+		val := fv
+		if r.name != "required" {
+			for val.Kind() == reflect.Ptr {
+				if val.IsNil() {
+					return nil
+				}
+				val = val.Elem()
+			}
+		}
+
+		if !r.fn(val, r.param) {
+			return &FieldError{
+				Tag:   r.name,
+				Param: r.param,
+				Value: valueString(val),
+				Err:   fmt.Errorf("failed on the '%s' rule", r.text),
+			}
+		}
+	}
+	return nil
+}
+
+func valueString(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fmt.Sprint(v.Interface())
+	}
+	return ""
+}
+
+func hasValue(v reflect.Value, _ string) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() > 0
+	case reflect.Invalid:
+		return false
+	default:
+		return !v.IsZero()
+	}
+}
+
+// compare returns the comparison of the size of v with param: the value of
+// numbers and durations, the length of strings, slices and maps. The
+// parameter was checked by checkCompareParam when the tag was parsed.
+func compare(v reflect.Value, param string) (int, bool) {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(param)
+		return cmpInt(v.Int(), int64(d)), err == nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		n, err := strconv.ParseInt(param, 10, 64)
+		return cmpInt(int64(utf8.RuneCountInString(v.String())), n), err == nil
+	case reflect.Slice, reflect.Map, reflect.Array:
+		n, err := strconv.ParseInt(param, 10, 64)
+		return cmpInt(int64(v.Len()), n), err == nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(param, 10, 64)
+		return cmpInt(v.Int(), n), err == nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		p, err := strconv.ParseUint(param, 10, 64)
+		switch n := v.Uint(); {
+		case n < p:
+			return -1, err == nil
+		case n > p:
+			return 1, err == nil
+		}
+		return 0, err == nil
+	case reflect.Float32, reflect.Float64:
+		p, err := strconv.ParseFloat(param, 64)
+		switch f := v.Float(); {
+		case f < p:
+			return -1, err == nil
+		case f > p:
+			return 1, err == nil
+		}
+		return 0, err == nil
+	}
+	return 0, false
+}
+
+// checkCompareParam checks the parameter of min, max and len.
+func checkCompareParam(t reflect.Type, param string) error {
+	var err error
+	switch t.Kind() {
+	case reflect.Interface:
+		// the type of the value is only known when it is validated.
+		return nil
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if t == durationType {
+			_, err = time.ParseDuration(param)
+		} else {
+			_, err = strconv.ParseInt(param, 10, 64)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		_, err = strconv.ParseUint(param, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		_, err = strconv.ParseFloat(param, 64)
+	default:
+		return fmt.Errorf("the rule does not apply to %s", t)
+	}
+	if err != nil {
+		return fmt.Errorf("bad %s parameter '%s'", t, param)
+	}
+	return nil
+}
+
+func cmpInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func isMin(v reflect.Value, param string) bool {
+	c, ok := compare(v, param)
+	return ok && c >= 0
+}
+
+func isMax(v reflect.Value, param string) bool {
+	c, ok := compare(v, param)
+	return ok && c <= 0
+}
+
+func isLen(v reflect.Value, param string) bool {
+	c, ok := compare(v, param)
+	return ok && c == 0
+}
+
+var emailRegexp = regexp.MustCompile(`^[a-zA-Z0-9.!#$%&'*+/=?^_{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+
+func isEmail(v reflect.Value, _ string) bool {
+	return v.Kind() == reflect.String && emailRegexp.MatchString(v.String())
+}
+
+// isOneOf checks the value against the space separated list in param.
+func isOneOf(v reflect.Value, param string) bool {
+	s := valueString(v)
+	for _, opt := range strings.Fields(param) {
+		if s == opt {
+			return true
+		}
+	}
+	return false
+}
+
+func matchRegex(val reflect.Value, param string) bool {
+	re, err := compileRegex(param)
+	return err == nil && val.Kind() == reflect.String && re.MatchString(val.String())
+}
+
+// checkRegexParam compiles the expression of a regex rule, so that it is
+// cached before the first validation.
+func checkRegexParam(_ reflect.Type, param string) error {
+	_, err := compileRegex(param)
+	return err
+}
+
+var regexps sync.Map // expression => *regexp.Regexp
+
+func compileRegex(expr string) (*regexp.Regexp, error) {
+	if re, ok := regexps.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	regexps.Store(expr, re)
+	return re, nil
+}
@@ -0,0 +1,135 @@
+package binding
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type item struct {
+	SKU string `binding:"required,regex=^[A-Z]{3}-[0-9]+$"`
+	Qty int    `binding:"min=1,max=10"`
+}
+
+type order struct {
+	Name    string  `binding:"required,min=2,max=5"`
+	Email   string  `binding:"omitempty,email"`
+	Status  string  `binding:"oneof=new paid"`
+	Code    string  `binding:"len=3"`
+	Note    *string `binding:"max=3"`
+	Items   []item  `binding:"required,max=2"`
+	Billing *item
+}
+
+func TestValidate(t *testing.T) {
+	v := NewValidator()
+	valid := order{
+		Name:   "bob",
+		Status: "paid",
+		Code:   "abc",
+		Items:  []item{{SKU: "ABC-1", Qty: 1}},
+	}
+	assert.NoError(t, v.Validate(&valid))
+	assert.NoError(t, v.Validate(valid))
+	assert.NoError(t, v.Validate(nil))
+	assert.NoError(t, v.Validate(3))
+
+	note := "long note"
+	invalid := order{
+		Name:    "a",
+		Email:   "bob",
+		Status:  "lost",
+		Code:    "abcd",
+		Note:    &note,
+		Items:   []item{{SKU: "ABC-1", Qty: 1}, {SKU: "abc", Qty: 11}},
+		Billing: &item{SKU: "ABC-2"},
+	}
+	err := v.Validate(&invalid)
+	errs, ok := err.(Errors)
+	assert.True(t, ok)
+
+	var fields, tags []string
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+		tags = append(tags, e.Tag)
+	}
+	assert.Equal(t, []string{"Name", "Email", "Status", "Code", "Note", "Items[1].SKU", "Items[1].Qty", "Billing.Qty"}, fields)
+	assert.Equal(t, []string{"min", "email", "oneof", "len", "max", "regex", "max", "min"}, tags)
+	assert.Equal(t, "2", errs[0].Param)
+	assert.Equal(t, "a", errs[0].Value)
+
+	assert.Error(t, v.Validate(&order{Name: "bob", Status: "new", Code: "abc"}))
+}
+
+func TestValidatorRegister(t *testing.T) {
+	v := NewValidator()
+	v.Register("even", func(value reflect.Value, _ string) bool {
+		return value.Int()%2 == 0
+	})
+
+	type number struct {
+		N int `binding:"even"`
+	}
+	assert.NoError(t, v.Validate(&number{N: 2}))
+	err := v.Validate(&number{N: 3})
+	assert.EqualError(t, err, "field 'N' with value '3': failed on the 'even' rule")
+
+	// the parsed rules are dropped when a rule is replaced.
+	v.Register("even", func(value reflect.Value, _ string) bool { return true })
+	assert.NoError(t, v.Validate(&number{N: 3}))
+}
+
+func TestValidateBadTags(t *testing.T) {
+	type unknown struct {
+		N int `binding:"odd"`
+	}
+	type badInt struct {
+		N int `binding:"required,min=abc"`
+	}
+	type badRegex struct {
+		S string `binding:"regex=[a-z"`
+	}
+	type nested struct {
+		Items []badInt
+	}
+
+	v := NewValidator()
+	for obj, msg := range map[interface{}]string{
+		&unknown{}:                       "binding: invalid rule 'odd' of field binding.unknown.N: undefined validation rule 'odd'",
+		&badInt{}:                        "binding: invalid rule 'min=abc' of field binding.badInt.N: bad int parameter 'abc'",
+		&badRegex{}:                      "binding: invalid rule 'regex=[a-z' of field binding.badRegex.S: error parsing regexp: missing closing ]: `[a-z`",
+		&nested{Items: []badInt{{N: 1}}}: "binding: invalid rule 'min=abc' of field binding.badInt.N: bad int parameter 'abc'",
+	} {
+		err := v.Validate(obj)
+		var te *TagError
+		assert.True(t, errors.As(err, &te), msg)
+		assert.EqualError(t, err, msg)
+		// the error is cached with the rules of the type.
+		assert.Equal(t, err, v.Validate(obj))
+	}
+}
+
+func TestErrorsRender(t *testing.T) {
+	err := NewValidator().Validate(&item{SKU: "ABC-1"})
+	w := httptest.NewRecorder()
+	assert.NoError(t, err.(Errors).Render(w))
+	assert.Equal(t, 400, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+
+	var body map[string][]map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, []map[string]string{{
+		"field":   "Qty",
+		"tag":     "min",
+		"param":   "1",
+		"value":   "0",
+		"message": "failed on the 'min=1' rule",
+	}}, body["errors"])
+
+	b, err := json.Marshal(&FieldError{Field: "Qty"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"field":"Qty","message":""}`, string(b))
+}
@@ -0,0 +1,44 @@
+// Package msgpack registers the MessagePack binding and renderer for the
+// application/msgpack and application/x-msgpack MIME types. Import it for
+// its side effects to bind and render MessagePack:
+//
+//	import _ "github.com/chen-zyc/gweb/codec/msgpack"
+//
+// The struct fields are named by their msgpack tag.
+package msgpack
+
+import (
+	"github.com/chen-zyc/gweb/binding"
+	"github.com/chen-zyc/gweb/render"
+	"github.com/vmihailenco/msgpack/v5"
+	"net/http"
+)
+
+// Binding decodes a MessagePack body.
+var Binding binding.Binding = msgpackBinding{}
+
+func init() {
+	for _, mime := range []string{binding.MIMEMsgPack, binding.MIMEMsgPack2} {
+		binding.Register(mime, Binding)
+		render.Register(mime, Render)
+	}
+}
+
+type msgpackBinding struct{}
+
+func (msgpackBinding) Name() string { return "msgpack" }
+
+func (msgpackBinding) Bind(req *http.Request, obj interface{}) error {
+	if req == nil || req.Body == nil {
+		return binding.ErrEmptyBody
+	}
+	return msgpack.NewDecoder(req.Body).Decode(obj)
+}
+
+// Render writes obj as MessagePack.
+func Render(obj interface{}) render.Render {
+	return render.RenderFunc(func(w http.ResponseWriter) error {
+		w.Header()["Content-Type"] = []string{"application/x-msgpack"}
+		return msgpack.NewEncoder(w).Encode(obj)
+	})
+}
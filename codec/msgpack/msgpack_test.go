@@ -0,0 +1,42 @@
+package msgpack
+
+import (
+	"bytes"
+	"github.com/chen-zyc/gweb/binding"
+	"github.com/chen-zyc/gweb/render"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type point struct {
+	X   int    `msgpack:"x"`
+	Y   int    `msgpack:"y"`
+	Tag string `msgpack:"tag"`
+}
+
+func TestRegistered(t *testing.T) {
+	assert.Equal(t, Binding, binding.Default(http.MethodPost, binding.MIMEMsgPack))
+	assert.Equal(t, Binding, binding.Default(http.MethodPost, binding.MIMEMsgPack2))
+	assert.Contains(t, render.MIMETypes(), binding.MIMEMsgPack)
+}
+
+func TestBinding(t *testing.T) {
+	data, err := msgpack.Marshal(point{1, 2, "a"})
+	assert.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodPost, "/", bytes.NewReader(data))
+	var p point
+	assert.NoError(t, Binding.Bind(req, &p))
+	assert.Equal(t, point{1, 2, "a"}, p)
+}
+
+func TestRender(t *testing.T) {
+	w := httptest.NewRecorder()
+	assert.NoError(t, Render(point{1, 2, "a"}).Render(w))
+	assert.Equal(t, "application/x-msgpack", w.Header().Get("Content-Type"))
+	var p point
+	assert.NoError(t, msgpack.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, point{1, 2, "a"}, p)
+}
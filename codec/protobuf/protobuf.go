@@ -0,0 +1,63 @@
+// Package protobuf registers the protobuf binding and renderer for the
+// application/protobuf and application/x-protobuf MIME types. Import it for
+// its side effects to bind and render protobuf messages:
+//
+//	import _ "github.com/chen-zyc/gweb/codec/protobuf"
+package protobuf
+
+import (
+	"fmt"
+	"github.com/chen-zyc/gweb/binding"
+	"github.com/chen-zyc/gweb/render"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net/http"
+)
+
+// Binding decodes a body in the protobuf wire format.
+var Binding binding.Binding = protobufBinding{}
+
+func init() {
+	for _, mime := range []string{binding.MIMEProtoBuf, binding.MIMEProtoBuf2} {
+		binding.Register(mime, Binding)
+		render.Register(mime, Render)
+	}
+}
+
+type protobufBinding struct{}
+
+func (protobufBinding) Name() string { return "protobuf" }
+
+// Bind decodes the body into obj, which must be a proto.Message.
+func (protobufBinding) Bind(req *http.Request, obj interface{}) error {
+	if req == nil || req.Body == nil {
+		return binding.ErrEmptyBody
+	}
+	msg, ok := obj.(proto.Message)
+	if !ok {
+		return fmt.Errorf("binding: %T is not a proto.Message", obj)
+	}
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(data, msg)
+}
+
+// Render writes obj, which must be a proto.Message, in the protobuf wire
+// format.
+func Render(obj interface{}) render.Render {
+	return render.RenderFunc(func(w http.ResponseWriter) error {
+		msg, ok := obj.(proto.Message)
+		if !ok {
+			return fmt.Errorf("render: %T is not a proto.Message", obj)
+		}
+		data, err := proto.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		w.Header()["Content-Type"] = []string{"application/x-protobuf"}
+		_, err = w.Write(data)
+		return err
+	})
+}
@@ -0,0 +1,47 @@
+package protobuf
+
+import (
+	"bytes"
+	"errors"
+	"github.com/chen-zyc/gweb/binding"
+	"github.com/chen-zyc/gweb/render"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegistered(t *testing.T) {
+	assert.Equal(t, Binding, binding.Default(http.MethodPost, binding.MIMEProtoBuf))
+	assert.Equal(t, Binding, binding.Default(http.MethodPost, binding.MIMEProtoBuf2))
+	assert.Contains(t, render.MIMETypes(), binding.MIMEProtoBuf)
+}
+
+func TestBinding(t *testing.T) {
+	data, err := proto.Marshal(wrapperspb.String("hello"))
+	assert.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodPost, "/", bytes.NewReader(data))
+	msg := &wrapperspb.StringValue{}
+	assert.NoError(t, Binding.Bind(req, msg))
+	assert.Equal(t, "hello", msg.GetValue())
+
+	req, _ = http.NewRequest(http.MethodPost, "/", bytes.NewReader(data))
+	var p struct{ X int }
+	assert.Error(t, Binding.Bind(req, &p))
+
+	req, _ = http.NewRequest(http.MethodPost, "/", nil)
+	assert.True(t, errors.Is(Binding.Bind(req, msg), binding.ErrEmptyBody))
+}
+
+func TestRender(t *testing.T) {
+	w := httptest.NewRecorder()
+	assert.NoError(t, Render(wrapperspb.String("hello")).Render(w))
+	assert.Equal(t, "application/x-protobuf", w.Header().Get("Content-Type"))
+	msg := &wrapperspb.StringValue{}
+	assert.NoError(t, proto.Unmarshal(w.Body.Bytes(), msg))
+	assert.Equal(t, "hello", msg.GetValue())
+
+	assert.EqualError(t, Render(1).Render(httptest.NewRecorder()), "render: int is not a proto.Message")
+}
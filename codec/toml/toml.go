@@ -0,0 +1,41 @@
+// Package toml registers the TOML binding and renderer for the
+// application/toml MIME type. Import it for its side effects to bind and
+// render TOML:
+//
+//	import _ "github.com/chen-zyc/gweb/codec/toml"
+package toml
+
+import (
+	"github.com/BurntSushi/toml"
+	"github.com/chen-zyc/gweb/binding"
+	"github.com/chen-zyc/gweb/render"
+	"net/http"
+)
+
+// Binding decodes a TOML body.
+var Binding binding.Binding = tomlBinding{}
+
+func init() {
+	binding.Register(binding.MIMETOML, Binding)
+	render.Register(binding.MIMETOML, Render)
+}
+
+type tomlBinding struct{}
+
+func (tomlBinding) Name() string { return "toml" }
+
+func (tomlBinding) Bind(req *http.Request, obj interface{}) error {
+	if req == nil || req.Body == nil {
+		return binding.ErrEmptyBody
+	}
+	_, err := toml.NewDecoder(req.Body).Decode(obj)
+	return err
+}
+
+// Render writes obj, which must be a struct or a map, as a TOML document.
+func Render(obj interface{}) render.Render {
+	return render.RenderFunc(func(w http.ResponseWriter) error {
+		w.Header()["Content-Type"] = []string{"application/toml; charset=utf-8"}
+		return toml.NewEncoder(w).Encode(obj)
+	})
+}
@@ -0,0 +1,39 @@
+package toml
+
+import (
+	"github.com/chen-zyc/gweb/binding"
+	"github.com/chen-zyc/gweb/render"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type point struct {
+	X   int    `toml:"x"`
+	Y   int    `toml:"y"`
+	Tag string `toml:"tag"`
+}
+
+func TestRegistered(t *testing.T) {
+	assert.Equal(t, Binding, binding.Default(http.MethodPost, binding.MIMETOML))
+	assert.Contains(t, render.MIMETypes(), binding.MIMETOML)
+}
+
+func TestBinding(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "/", strings.NewReader("x = 1\ny = 2\ntag = \"a\"\n"))
+	var p point
+	assert.NoError(t, Binding.Bind(req, &p))
+	assert.Equal(t, point{1, 2, "a"}, p)
+
+	req, _ = http.NewRequest(http.MethodPost, "/", strings.NewReader("x = \n"))
+	assert.Error(t, Binding.Bind(req, &p))
+}
+
+func TestRender(t *testing.T) {
+	w := httptest.NewRecorder()
+	assert.NoError(t, render.MIMERender(binding.MIMETOML, point{1, 2, "a"}).Render(w))
+	assert.Equal(t, "application/toml; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "x = 1\ny = 2\ntag = \"a\"\n", w.Body.String())
+}
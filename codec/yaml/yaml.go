@@ -0,0 +1,46 @@
+// Package yaml registers the YAML binding and renderer for the
+// application/yaml and application/x-yaml MIME types. Import it for its
+// side effects to bind and render YAML:
+//
+//	import _ "github.com/chen-zyc/gweb/codec/yaml"
+package yaml
+
+import (
+	"github.com/chen-zyc/gweb/binding"
+	"github.com/chen-zyc/gweb/render"
+	"gopkg.in/yaml.v3"
+	"net/http"
+)
+
+// Binding decodes a YAML body.
+var Binding binding.Binding = yamlBinding{}
+
+func init() {
+	for _, mime := range []string{binding.MIMEYAML, binding.MIMEYAML2} {
+		binding.Register(mime, Binding)
+		render.Register(mime, Render)
+	}
+}
+
+type yamlBinding struct{}
+
+func (yamlBinding) Name() string { return "yaml" }
+
+func (yamlBinding) Bind(req *http.Request, obj interface{}) error {
+	if req == nil || req.Body == nil {
+		return binding.ErrEmptyBody
+	}
+	return yaml.NewDecoder(req.Body).Decode(obj)
+}
+
+// Render writes obj as a YAML document.
+func Render(obj interface{}) render.Render {
+	return render.RenderFunc(func(w http.ResponseWriter) error {
+		w.Header()["Content-Type"] = []string{"application/yaml; charset=utf-8"}
+		enc := yaml.NewEncoder(w)
+		if err := enc.Encode(obj); err != nil {
+			return err
+		}
+		return enc.Close()
+	})
+}
@@ -0,0 +1,40 @@
+package yaml
+
+import (
+	"github.com/chen-zyc/gweb/binding"
+	"github.com/chen-zyc/gweb/render"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type point struct {
+	X   int    `yaml:"x"`
+	Y   int    `yaml:"y"`
+	Tag string `yaml:"tag"`
+}
+
+func TestRegistered(t *testing.T) {
+	assert.Equal(t, Binding, binding.Default(http.MethodPost, binding.MIMEYAML))
+	assert.Equal(t, Binding, binding.Default(http.MethodPost, binding.MIMEYAML2))
+	assert.Contains(t, render.MIMETypes(), binding.MIMEYAML2)
+}
+
+func TestBinding(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "/", strings.NewReader("x: 1\ny: 2\ntag: a\n"))
+	var p point
+	assert.NoError(t, Binding.Bind(req, &p))
+	assert.Equal(t, point{1, 2, "a"}, p)
+
+	req, _ = http.NewRequest(http.MethodPost, "/", strings.NewReader("x: one\n"))
+	assert.Error(t, Binding.Bind(req, &p))
+}
+
+func TestRender(t *testing.T) {
+	w := httptest.NewRecorder()
+	assert.NoError(t, render.MIMERender(binding.MIMEYAML, map[string]int{"a": 1, "b": 2}).Render(w))
+	assert.Equal(t, "application/yaml; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "a: 1\nb: 2\n", w.Body.String())
+}
@@ -0,0 +1,118 @@
+package gweb
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// StrongETag returns a strong entity tag made of the hash of data, which
+// changes whenever a byte of the representation changes.
+func StrongETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// WeakETag returns a weak entity tag made of the hash of data, for
+// representations which are equivalent but not byte for byte identical,
+// e.g. compressed ones.
+func WeakETag(data []byte) string {
+	return "W/" + StrongETag(data)
+}
+
+// VersionETag returns the entity tag of a version supplied by the
+// application, such as a revision number or an update timestamp.
+func VersionETag(version string, weak bool) string {
+	etag := `"` + strings.ReplaceAll(version, `"`, "") + `"`
+	if weak {
+		return "W/" + etag
+	}
+	return etag
+}
+
+// CheckPreconditions sets the ETag and Last-Modified headers of the response
+// and evaluates the conditional headers of the request against them, in the
+// order of RFC 7232, section 6. An empty etag or a zero lastModified is
+// skipped, the representation is missing when both are, so that "*" only
+// matches an existing one. It returns true after writing a 304 Not Modified or a
+// 412 Precondition Failed response, in which case the handler must not
+// write the body. Unsafe methods should call it before changing the
+// resource, so that If-Match protects against lost updates.
+func (c *Context) CheckPreconditions(etag string, lastModified time.Time) bool {
+	h := c.resp.Header()
+	if etag != "" {
+		h.Set("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		h.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	switch checkPreconditions(c.req, etag, lastModified) {
+	case http.StatusNotModified:
+		// a 304 response has no body, the headers describing it are dropped.
+		delete(h, "Content-Type")
+		delete(h, "Content-Length")
+		c.resp.WriteHeader(http.StatusNotModified)
+		c.resp.WriteHeaderNow()
+		return true
+	case http.StatusPreconditionFailed:
+		c.resp.WriteHeader(http.StatusPreconditionFailed)
+		c.resp.WriteHeaderNow()
+		return true
+	}
+	return false
+}
+
+// checkPreconditions returns 304, 412 or 0 when the request can proceed.
+func checkPreconditions(req *http.Request, etag string, lastModified time.Time) int {
+	safe := req.Method == http.MethodGet || req.Method == http.MethodHead
+	exists := etag != "" || !lastModified.IsZero()
+
+	if im := req.Header.Get("If-Match"); im != "" {
+		if !matchETag(im, etag, exists, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if ius := req.Header.Get("If-Unmodified-Since"); ius != "" && !lastModified.IsZero() {
+		if t, err := http.ParseTime(ius); err == nil && lastModified.Truncate(time.Second).After(t) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		if matchETag(inm, etag, exists, true) {
+			if safe {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if ims := req.Header.Get("If-Modified-Since"); ims != "" && safe && !lastModified.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil && !lastModified.Truncate(time.Second).After(t) {
+			return http.StatusNotModified
+		}
+	}
+	return 0
+}
+
+// matchETag reports whether the list of entity tags of a conditional header
+// contains etag. The weak comparison ignores the W/ prefixes, the strong
+// one never matches a weak tag. "*" matches the current representation if
+// it exists, even without an entity tag.
+func matchETag(list, etag string, exists, weak bool) bool {
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		switch {
+		case candidate == "*":
+			return exists
+		case etag == "": // only "*" can match
+		case weak:
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		case !strings.HasPrefix(candidate, "W/") && !strings.HasPrefix(etag, "W/") && candidate == etag:
+			return true
+		}
+	}
+	return false
+}
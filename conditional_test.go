@@ -0,0 +1,96 @@
+package gweb
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMatchETag(t *testing.T) {
+	assert.True(t, matchETag(`"a"`, `"a"`, true, false))
+	assert.True(t, matchETag(`"b", "a"`, `"a"`, true, false))
+	assert.True(t, matchETag(`*`, `"a"`, true, false))
+	assert.True(t, matchETag(`*`, ``, true, false))
+	assert.False(t, matchETag(`*`, ``, false, false))
+	assert.False(t, matchETag(`W/"a"`, `"a"`, true, false))
+	assert.False(t, matchETag(`"a"`, `W/"a"`, true, false))
+	assert.True(t, matchETag(`W/"a"`, `"a"`, true, true))
+	assert.True(t, matchETag(`"a"`, `W/"a"`, true, true))
+	assert.False(t, matchETag(`"a"`, ``, true, true))
+	assert.Equal(t, `W/"v1"`, VersionETag(`v"1`, true))
+}
+
+func TestContextCheckPreconditions(t *testing.T) {
+	version := "3"
+	updated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := NewServer()
+	s.GET("/doc", func(c *Context) {
+		if c.CheckPreconditions(VersionETag(version, false), updated) {
+			return
+		}
+		c.JSON(http.StatusOK, H{"version": version})
+	})
+	s.PUT("/doc", func(c *Context) {
+		if c.CheckPreconditions(VersionETag(version, false), updated) {
+			return
+		}
+		version = "4"
+		c.Status(http.StatusNoContent)
+	})
+
+	perform := func(method string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/doc", nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w
+	}
+
+	w := perform(MethodGet)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+	assert.Equal(t, "Wed, 01 May 2024 12:00:00 GMT", w.Header().Get("Last-Modified"))
+
+	w = perform(MethodGet, "If-None-Match", `"3"`)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	// the lost update is prevented, the first one succeeds.
+	w = perform(MethodPut, "If-Match", `"3"`)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = perform(MethodPut, "If-Match", `"3"`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	// If-None-Match of an unsafe method fails instead of returning 304.
+	w = perform(MethodPut, "If-None-Match", `*`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	// If-Modified-Since only applies to GET and HEAD.
+	w = perform(MethodPut, "If-Modified-Since", updated.Format(http.TimeFormat))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// "*" matches an existing representation without entity tag, not a
+	// missing one.
+	s.DELETE("/doc", func(c *Context) {
+		if c.CheckPreconditions("", updated) {
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+	s.POST("/doc", func(c *Context) {
+		if c.CheckPreconditions("", time.Time{}) {
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+	w = perform(MethodDelete, "If-Match", `*`)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = perform(MethodDelete, "If-None-Match", `*`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = perform(MethodPost, "If-Match", `*`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = perform(MethodPost, "If-None-Match", `*`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
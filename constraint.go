@@ -0,0 +1,177 @@
+package gweb
+
+import (
+	"regexp"
+	"strings"
+)
+
+// paramConstraint restricts the values matched by a route parameter. It is
+// written after the name of the parameter between angle brackets, either a
+// type such as <int> or a regular expression matching the whole segment,
+// e.g. "/users/:id<int>" or "/posts/:slug<[a-z0-9-]+>".
+type paramConstraint struct {
+	expr  string // as written between the brackets
+	match func(value string) bool
+}
+
+// paramTypes are the named constraints.
+var paramTypes = map[string]func(value string) bool{
+	"int": func(v string) bool {
+		return isDigits(strings.TrimPrefix(v, "-"))
+	},
+	"uint": isDigits,
+	"alpha": func(v string) bool {
+		return v != "" && strings.Trim(v, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ") == ""
+	},
+	"alnum": func(v string) bool {
+		return v != "" && strings.Trim(v, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789") == ""
+	},
+	"uuid": uuidRegexp.MatchString,
+}
+
+var uuidRegexp = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func isDigits(v string) bool {
+	return v != "" && strings.Trim(v, "0123456789") == ""
+}
+
+func newParamConstraint(expr string) (*paramConstraint, error) {
+	if match, ok := paramTypes[expr]; ok {
+		return &paramConstraint{expr: expr, match: match}, nil
+	}
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, err
+	}
+	return &paramConstraint{expr: expr, match: re.MatchString}, nil
+}
+
+// accepts reports whether the node can match the value of its parameter.
+func (n *Node) accepts(value string) bool {
+	return n.constraint == nil || n.constraint.match(value)
+}
+
+func (c *paramConstraint) String() string {
+	if c == nil {
+		return ""
+	}
+	return "<" + c.expr + ">"
+}
+
+// routePath is a path added to the tree. The tree indexes the path without
+// the constraints, which are attached to the parameter nodes.
+type routePath struct {
+	full        string                   // as registered
+	clean       string                   // without the constraints and the '?'
+	constraints map[int]*paramConstraint // by offset of the wildcard in clean
+	optional    []int                    // offsets in clean of the optional segments
+}
+
+// parseRoutePath removes the constraints and the optional markers from path.
+// It panics if they are malformed.
+func parseRoutePath(path string) *routePath {
+	rp := &routePath{full: path}
+	if !strings.ContainsAny(path, "<?") {
+		rp.clean = path
+		return rp
+	}
+
+	var clean strings.Builder
+	wildcard := -1 // offset in clean of the wildcard of the current segment
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case c == ':' || c == '*':
+			wildcard = clean.Len()
+		case c == '/':
+			wildcard = -1
+		case c == '<':
+			if wildcard < 0 {
+				panic("a constraint must follow a parameter name in path '" + path + "'")
+			}
+			if clean.String()[wildcard] == '*' {
+				panic("catch-all parameters can not have a constraint in path '" + path + "'")
+			}
+			// the expression can contain brackets, e.g. a named group.
+			end, depth := i+1, 1
+			for ; end < len(path) && depth > 0; end++ {
+				switch path[end] {
+				case '<':
+					depth++
+				case '>':
+					depth--
+				case '/':
+					panic("a constraint can not contain '/' in path '" + path + "'")
+				}
+			}
+			if depth > 0 {
+				panic("unterminated constraint in path '" + path + "'")
+			}
+			if end < len(path) && path[end] != '/' && path[end] != '?' {
+				panic("a constraint must end its path segment in path '" + path + "'")
+			}
+			constraint, err := newParamConstraint(path[i+1 : end-1])
+			if err != nil {
+				panic("invalid constraint '" + path[i:end] + "' in path '" + path + "': " + err.Error())
+			}
+			if rp.constraints == nil {
+				rp.constraints = make(map[int]*paramConstraint)
+			}
+			rp.constraints[wildcard] = constraint
+			i = end - 1
+			continue
+		case c == '?':
+			if wildcard < 0 || clean.String()[wildcard] != ':' {
+				panic("only parameters can be optional in path '" + path + "'")
+			}
+			if i+1 < len(path) && path[i+1] != '/' {
+				panic("'?' must end its path segment in path '" + path + "'")
+			}
+			if wildcard == 0 || clean.String()[wildcard-1] != '/' {
+				panic("an optional parameter must be a whole path segment in path '" + path + "'")
+			}
+			rp.optional = append(rp.optional, wildcard-1)
+			continue
+		}
+		clean.WriteByte(path[i])
+	}
+	rp.clean = clean.String()
+
+	if len(rp.optional) > 0 {
+		segments := strings.Count(rp.clean[rp.optional[0]:], "/")
+		if segments != len(rp.optional) {
+			panic("only the last segments of a path can be optional in path '" + path + "'")
+		}
+	}
+	return rp
+}
+
+// expand returns the paths matched by rp: the path with all its optional
+// segments, then without the last one and so on.
+func (rp *routePath) expand() []*routePath {
+	paths := []*routePath{rp}
+	for i := len(rp.optional) - 1; i >= 0; i-- {
+		clean := rp.clean[:rp.optional[i]]
+		if clean == "" {
+			clean = "/"
+		}
+		paths = append(paths, &routePath{full: rp.full, clean: clean, constraints: rp.constraints})
+	}
+	return paths
+}
+
+// isOptional reports whether the segment starting at offset i in the clean
+// path is optional.
+func (rp *routePath) isOptional(i int) bool {
+	for _, o := range rp.optional {
+		if o == i {
+			return true
+		}
+	}
+	return false
+}
+
+// constraintAt returns the constraint of the wildcard starting path, a
+// suffix of the clean path.
+func (rp *routePath) constraintAt(path string) *paramConstraint {
+	return rp.constraints[len(rp.clean)-len(path)]
+}
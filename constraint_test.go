@@ -0,0 +1,113 @@
+package gweb
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+// paramsHandler returns a handler writing name and the params.
+func paramsHandler(name string) Handler {
+	return func(c *Context) {
+		c.String(http.StatusOK, "%s %v", name, c.params)
+	}
+}
+
+func TestParamConstraints(t *testing.T) {
+	s := NewServer()
+	s.GET("/users/:id<int>", paramsHandler("id"))
+	s.GET("/users/:uuid<uuid>", paramsHandler("uuid"))
+	s.GET("/users/:name", paramsHandler("name"))
+	s.GET("/posts/:slug<[a-z-]+>/comments/:n<uint>", paramsHandler("comments"))
+	s.GET("/posts/:slug<[a-z-]+>/edit", paramsHandler("edit"))
+	s.GET("/posts/:id<int>/edit", paramsHandler("edit-id"))
+	s.GET("/tags/:tag<(?P<word>[a-z]+)>", paramsHandler("tag"))
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/users/42", http.StatusOK, "id [{id 42}]"},
+		{"/users/-7", http.StatusOK, "id [{id -7}]"},
+		{"/users/6ba7b810-9dad-11d1-80b4-00c04fd430c8", http.StatusOK, "uuid [{uuid 6ba7b810-9dad-11d1-80b4-00c04fd430c8}]"},
+		// falls through to the unconstrained param.
+		{"/users/abc", http.StatusOK, "name [{name abc}]"},
+		{"/posts/hello-go/comments/3", http.StatusOK, "comments [{slug hello-go} {n 3}]"},
+		{"/posts/hello-go/comments/x", http.StatusNotFound, ""},
+		{"/posts/Hello/edit", http.StatusNotFound, ""},
+		{"/posts/hello/edit", http.StatusOK, "edit [{slug hello}]"},
+		// the first param accepting the value has no route for the rest.
+		{"/posts/12/edit", http.StatusOK, "edit-id [{id 12}]"},
+		{"/tags/go", http.StatusOK, "tag [{tag go}]"},
+		{"/tags/go1", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := performRequest(s, MethodGet, tt.path)
+		assert.Equal(t, tt.code, w.Code, tt.path)
+		if tt.body != "" {
+			assert.Equal(t, tt.body, w.Body.String(), tt.path)
+		}
+	}
+
+	handlers, _, fullPath, _ := s.loadTrees()[MethodGet].Find("/users/42")
+	assert.NotNil(t, handlers)
+	assert.Equal(t, "/users/:id<int>", fullPath)
+}
+
+func TestParamConstraintsRedirects(t *testing.T) {
+	s := NewServer()
+	s.GET("/items/:id<int>/", paramsHandler("item"))
+	s.GET("/Docs/:page<[a-z]+>", paramsHandler("docs"))
+
+	w := performRequest(s, MethodGet, "/items/3")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/items/3/", w.Header().Get("Location"))
+
+	w = performRequest(s, MethodGet, "/docs/intro")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/Docs/intro", w.Header().Get("Location"))
+
+	// the constraint is checked by the case-insensitive lookup too.
+	w = performRequest(s, MethodGet, "/docs/INTRO")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestParamConstraintConflicts(t *testing.T) {
+	s := NewServer()
+	s.GET("/users/:id<int>", emptyHandler)
+	s.GET("/users/:id<int>/posts", emptyHandler)
+	s.GET("/users/:id", emptyHandler)
+
+	assert.Equal(t, "':num' in new path '/users/:num<int>' conflicts with existing wildcard ':id' in existing prefix '/users/:id'", panicReason(func() {
+		s.GET("/users/:num<int>", emptyHandler)
+	}))
+	assert.Panics(t, func() { s.GET("/users/:name", emptyHandler) })
+	assert.Panics(t, func() { s.GET("/users/*all", emptyHandler) })
+	assert.Equal(t, "a handle is already registered for path '/users/:id<int>'", panicReason(func() {
+		s.GET("/users/:id<int>", emptyHandler)
+	}))
+
+	for path, msg := range map[string]string{
+		"/a/:id<int":      "unterminated constraint in path '/a/:id<int'",
+		"/a/:id<[a-z]+>x": "a constraint must end its path segment in path '/a/:id<[a-z]+>x'",
+		"/a/:id<[a/z]>":   "a constraint can not contain '/' in path '/a/:id<[a/z]>'",
+		"/a/*all<int>":    "catch-all parameters can not have a constraint in path '/a/*all<int>'",
+		"/a/<int>":        "a constraint must follow a parameter name in path '/a/<int>'",
+		"/a/:id<[a-z>":    "invalid constraint '<[a-z>' in path '/a/:id<[a-z>': error parsing regexp: missing closing ]: `[a-z)$`",
+	} {
+		assert.Equal(t, msg, panicReason(func() { NewServer().GET(path, emptyHandler) }), path)
+	}
+}
+
+func TestURLWithConstraints(t *testing.T) {
+	s := NewServer()
+	s.GET("/users/:id<int>/files/*path", emptyHandler).Name("file")
+
+	u, err := s.URL("file", "id", 7, "path", "/a/b")
+	assert.NoError(t, err)
+	assert.Equal(t, "/users/7/files/a/b", u)
+
+	_, err = s.URL("file", "id", "abc", "path", "/a")
+	assert.EqualError(t, err, "gweb: value 'abc' of parameter 'id' does not match <int> in path '/users/:id<int>/files/*path'")
+}
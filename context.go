@@ -1,11 +1,25 @@
 package gweb
 
 import (
+	"context"
+	"errors"
+	"github.com/chen-zyc/gweb/binding"
 	"github.com/chen-zyc/gweb/render"
+	"github.com/chen-zyc/gweb/websocket"
+	"io"
+	"math"
+	"net"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
+	"time"
 )
 
+// abortIndex is assigned to curHandlerIndex when the chain is aborted, it is
+// larger than any possible number of handlers.
+const abortIndex int = math.MaxInt32 / 2
+
 // Param is a single URL parameter, consisting of a key and a value.
 type Param struct {
 	Key   string
@@ -31,21 +45,52 @@ func (ps Params) ByName(name string) string {
 type Context struct {
 	s               *Server
 	req             *http.Request
-	resp            http.ResponseWriter
+	resp            ResponseWriter
+	writermem       responseWriter
 	params          Params
+	fullPath        string
 	handlers        Handlers
+	handlerName     string // set by Copy
+	ws              *websocket.Conn
+	errs            []error
 	curHandlerIndex int
-	userData        map[string]interface{}
+	userMu          sync.RWMutex
+	userData        map[interface{}]interface{}
 }
 
+var _ context.Context = (*Context)(nil)
+
 func (c *Context) reset(req *http.Request, resp http.ResponseWriter) {
 	c.req = req
-	c.resp = resp
+	c.writermem.reset(resp)
+	c.resp = &c.writermem
 	c.params = c.params[:0]
+	c.fullPath = ""
+	// drop the values of the previous request. Goroutines outliving a
+	// request must use Context.Copy, which snapshots them.
+	c.userMu.Lock()
+	c.userData = nil
+	c.userMu.Unlock()
 	c.handlers = nil
+	c.handlerName = ""
+	c.ws = nil
+	c.errs = c.errs[:0]
 	c.curHandlerIndex = -1
 }
 
+// release drops the references to the request so that they are not kept
+// alive by the pool.
+func (c *Context) release() {
+	c.req = nil
+	c.writermem.ResponseWriter = nil
+	c.handlers = nil
+	c.ws = nil
+}
+
+// Next executes the pending handlers in the chain.
+// Once the chain is aborted, Next does nothing: the remaining handlers are
+// skipped, while the handlers already running continue after their call to
+// Next returns.
 func (c *Context) Next() {
 	c.curHandlerIndex++
 	// 使用for，这样的话即使handler没有主动调用 Next，也能够保证剩余的handler被执行。
@@ -54,10 +99,67 @@ func (c *Context) Next() {
 	}
 }
 
+// Abort prevents the pending handlers from being called. It does not stop
+// the current handler.
+func (c *Context) Abort() {
+	c.curHandlerIndex = abortIndex
+}
+
+// AbortWithStatus calls Abort and writes the status code.
+func (c *Context) AbortWithStatus(code int) {
+	c.Status(code)
+	c.Abort()
+}
+
+// AbortWithStatusJSON calls Abort and renders obj as JSON with the status code.
+func (c *Context) AbortWithStatusJSON(code int, obj interface{}) {
+	c.Abort()
+	c.JSON(code, obj)
+}
+
+// IsAborted reports whether the chain was aborted.
+func (c *Context) IsAborted() bool {
+	return c.curHandlerIndex >= abortIndex
+}
+
 // =================================
 // ======= input data ==============
 // =================================
 
+// Request returns the http request.
+func (c *Context) Request() *http.Request {
+	return c.req
+}
+
+// FullPath returns the registered path of the matched route, e.g.
+// "/users/:id". It is empty if no route matched.
+func (c *Context) FullPath() string {
+	return c.fullPath
+}
+
+// ClientIP returns the IP of the client. If Server.ForwardedByClientIP is
+// enabled, the first address of the X-Forwarded-For header or the X-Real-IP
+// header is used if present.
+func (c *Context) ClientIP() string {
+	if c.s.ForwardedByClientIP {
+		if forwarded := c.req.Header.Get("X-Forwarded-For"); forwarded != "" {
+			if i := strings.IndexByte(forwarded, ','); i >= 0 {
+				forwarded = forwarded[:i]
+			}
+			if ip := strings.TrimSpace(forwarded); ip != "" {
+				return ip
+			}
+		}
+		if ip := strings.TrimSpace(c.req.Header.Get("X-Real-Ip")); ip != "" {
+			return ip
+		}
+	}
+	if ip, _, err := net.SplitHostPort(strings.TrimSpace(c.req.RemoteAddr)); err == nil {
+		return ip
+	}
+	return c.req.RemoteAddr
+}
+
 func (c *Context) Param(name string) string {
 	return c.params.ByName(name)
 }
@@ -101,7 +203,7 @@ func (c *Context) GetPostFormArray(key string) ([]string, bool) {
 	if exist && len(arr) > 0 {
 		return arr, true
 	}
-	c.req.ParseMultipartForm(32 << 20) // 32MB
+	c.req.ParseMultipartForm(c.s.MaxMultipartMemory)
 	if c.req.MultipartForm != nil && c.req.MultipartForm.File != nil {
 		if arr = c.req.MultipartForm.Value[key]; len(arr) > 0 {
 			return arr, true
@@ -129,10 +231,117 @@ func (c *Context) PostForm(key string) string {
 	return val
 }
 
+// ContentType returns the media type of the request's Content-Type header,
+// without parameters such as the charset.
+func (c *Context) ContentType() string {
+	return filterFlags(c.req.Header.Get("Content-Type"))
+}
+
+// Bind decodes the request into obj with the binding chosen by the request
+// method and Content-Type: GET and HEAD requests are bound from the query
+// string, the bodies of a type registered with binding.Register (JSON and
+// XML, plus the formats of the imported codec packages) are decoded and
+// everything else is bound as a form. See binding.Default.
+//
+// The decoded value is validated against the rules of its `binding` tags,
+// see binding.Validator.
+func (c *Context) Bind(obj interface{}) error {
+	return c.BindWith(obj, binding.Default(c.req.Method, c.ContentType()))
+}
+
+func (c *Context) BindJSON(obj interface{}) error {
+	return c.BindWith(obj, binding.JSON)
+}
+
+func (c *Context) BindXML(obj interface{}) error {
+	return c.BindWith(obj, binding.XML)
+}
+
+// BindYAML decodes the body as YAML, the codec/yaml package must be
+// imported. It fails with binding.ErrNoBinding otherwise, as the other
+// bindings of a codec package below.
+func (c *Context) BindYAML(obj interface{}) error {
+	return c.BindWith(obj, binding.MIMEBinding(MIMEYAML))
+}
+
+// BindTOML decodes the body as TOML, the codec/toml package must be imported.
+func (c *Context) BindTOML(obj interface{}) error {
+	return c.BindWith(obj, binding.MIMEBinding(MIMETOML))
+}
+
+// BindMsgPack decodes the body as MessagePack, the codec/msgpack package
+// must be imported.
+func (c *Context) BindMsgPack(obj interface{}) error {
+	return c.BindWith(obj, binding.MIMEBinding(MIMEMsgPack))
+}
+
+// BindProtoBuf decodes the body into obj, which must be a proto.Message.
+// The codec/protobuf package must be imported.
+func (c *Context) BindProtoBuf(obj interface{}) error {
+	return c.BindWith(obj, binding.MIMEBinding(MIMEProtoBuf))
+}
+
+func (c *Context) BindQuery(obj interface{}) error {
+	return c.BindWith(obj, binding.Query)
+}
+
+func (c *Context) BindForm(obj interface{}) error {
+	return c.BindWith(obj, binding.Form)
+}
+
+// BindURI decodes the path parameters into obj using the `uri` tags.
+func (c *Context) BindURI(obj interface{}) error {
+	params := make(map[string][]string, len(c.params))
+	for _, p := range c.params {
+		params[p.Key] = append(params[p.Key], p.Value)
+	}
+	if err := binding.URI.BindURI(params, obj); err != nil {
+		return err
+	}
+	return c.s.validator.Validate(obj)
+}
+
+// BindWith decodes the request into obj with the given binding and
+// validates it. Failures are reported as binding.Errors, which can be
+// rendered as a 400 response with c.Render.
+func (c *Context) BindWith(obj interface{}, b binding.Binding) error {
+	if b == binding.Form {
+		// parsed here with the limit of the server, the binding keeps the
+		// form already parsed.
+		if _, err := c.MultipartForm(); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			return err
+		}
+	}
+	if err := b.Bind(c.req, obj); err != nil {
+		return err
+	}
+	return c.s.validator.Validate(obj)
+}
+
 // =================================
 // ======= response ================
 // =================================
 
+// Writer returns the writer of the response.
+func (c *Context) Writer() ResponseWriter {
+	return c.resp
+}
+
+// SetWriter replaces the writer of the response, e.g. by a middleware
+// buffering or transforming the body. The middleware should restore the
+// previous writer once the handlers it wraps have returned.
+func (c *Context) SetWriter(w ResponseWriter) {
+	c.resp = w
+}
+
+// WebSocket returns the connection upgraded by a route registered with
+// RouterGroup.WebSocket, nil for the other routes.
+func (c *Context) WebSocket() *websocket.Conn {
+	return c.ws
+}
+
+// Status sets the status code of the response. It can be called again to
+// change the code until the body is written.
 func (c *Context) Status(code int) {
 	c.resp.WriteHeader(code)
 }
@@ -145,12 +354,29 @@ func (c *Context) Header(key, value string) {
 	}
 }
 
+// Render writes the response with r. On a copied context nothing is
+// written, ErrCopiedContext is recorded with Error instead.
 func (c *Context) Render(r render.Render) {
 	if err := r.Render(c.resp); err != nil {
+		if errors.Is(err, ErrCopiedContext) {
+			c.Error(err)
+			return
+		}
 		panic(err)
 	}
 }
 
+// Error records an error of the request, e.g. for a middleware logging them
+// once the handlers returned.
+func (c *Context) Error(err error) {
+	c.errs = append(c.errs, err)
+}
+
+// Errors returns the errors recorded with Error.
+func (c *Context) Errors() []error {
+	return c.errs
+}
+
 func (c *Context) String(code int, format string, args ...interface{}) {
 	c.Status(code)
 	c.Render(render.StringRender(format, args...))
@@ -161,16 +387,128 @@ func (c *Context) JSON(code int, obj interface{}) {
 	c.Render(render.JSONRender(obj))
 }
 
+// IndentedJSON renders obj as indented JSON, for humans reading the response.
+func (c *Context) IndentedJSON(code int, obj interface{}) {
+	c.Status(code)
+	c.Render(render.IndentedJSONRender(obj))
+}
+
+// SecureJSON renders obj as JSON prefixed with Server.SecureJSONPrefix.
+func (c *Context) SecureJSON(code int, obj interface{}) {
+	c.Status(code)
+	c.Render(render.SecureJSONRender(c.s.SecureJSONPrefix, obj))
+}
+
+// JSONP renders obj as JSON wrapped in the function named by the "callback"
+// query parameter, or as plain JSON without it. An invalid callback results
+// in a 400 response.
+func (c *Context) JSONP(code int, obj interface{}) {
+	callback := c.Query("callback")
+	if callback != "" && !render.ValidCallback(callback) {
+		c.String(http.StatusBadRequest, "invalid JSONP callback")
+		return
+	}
+	c.Status(code)
+	c.Render(render.JSONPRender(callback, obj))
+}
+
+// AsciiJSON renders obj as JSON with the non-ASCII characters escaped.
+func (c *Context) AsciiJSON(code int, obj interface{}) {
+	c.Status(code)
+	c.Render(render.AsciiJSONRender(obj))
+}
+
+// PureJSON renders obj as JSON without escaping the HTML characters.
+func (c *Context) PureJSON(code int, obj interface{}) {
+	c.Status(code)
+	c.Render(render.PureJSONRender(obj))
+}
+
 func (c *Context) XML(code int, obj interface{}) {
 	c.Status(code)
 	c.Render(render.XMLRender(obj))
 }
 
+// YAML renders obj as YAML, the codec/yaml package must be imported. The
+// rendering fails with render.ErrNoRenderer otherwise, as the other
+// renderers of a codec package below.
+func (c *Context) YAML(code int, obj interface{}) {
+	c.Status(code)
+	c.Render(render.MIMERender(MIMEYAML, obj))
+}
+
+// TOML renders obj as TOML, the codec/toml package must be imported.
+func (c *Context) TOML(code int, obj interface{}) {
+	c.Status(code)
+	c.Render(render.MIMERender(MIMETOML, obj))
+}
+
+// MsgPack renders obj as MessagePack, the codec/msgpack package must be
+// imported.
+func (c *Context) MsgPack(code int, obj interface{}) {
+	c.Status(code)
+	c.Render(render.MIMERender(MIMEMsgPack, obj))
+}
+
+// ProtoBuf renders obj, which must be a proto.Message. The codec/protobuf
+// package must be imported.
+func (c *Context) ProtoBuf(code int, obj interface{}) {
+	c.Status(code)
+	c.Render(render.MIMERender(MIMEProtoBuf, obj))
+}
+
 func (c *Context) HTML(code int, name string, data interface{}) {
 	c.Status(code)
 	c.Render(render.HTMLRender(c.s.htmlTemplate, name, data))
 }
 
+// SSEvent writes a Server-Sent Event with the name and data. Use c.Render
+// with a render.SSEvent to set an ID or a retry hint.
+func (c *Context) SSEvent(name string, data interface{}) {
+	c.Render(render.SSEvent{Event: name, Data: data})
+}
+
+// LastEventID returns the ID of the last event received by a reconnecting
+// Server-Sent Events client, from the Last-Event-ID header.
+func (c *Context) LastEventID() string {
+	return c.req.Header.Get("Last-Event-ID")
+}
+
+// Stream calls step repeatedly and flushes the response after each call,
+// until step returns false or the client disconnects. It returns true if the
+// client disconnected. A step waiting for data should also wait on c.Done()
+// to return when the client goes away:
+//
+//	c.Stream(func(w io.Writer) bool {
+//		select {
+//		case msg := <-messages:
+//			c.SSEvent("message", msg)
+//			return true
+//		case <-c.Done():
+//			return false
+//		}
+//	})
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	done := c.req.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		default:
+			keepOpen := step(c.resp)
+			c.resp.Flush()
+			if !keepOpen {
+				select {
+				case <-done:
+					return true
+				default:
+					return false
+				}
+			}
+		}
+	}
+}
+
 func (c *Context) File(filePath string) {
 	http.ServeFile(c.resp, c.req, filePath)
 }
@@ -207,17 +545,122 @@ func (c *Context) RawCookie(name string) (*http.Cookie, error) {
 // ======= user data ===============
 // =================================
 
+// The user data is scoped to the request and can be read concurrently by
+// goroutines started by the handlers while the request is being served.
+
 func (c *Context) UserData(key string) (data interface{}, exist bool) {
-	if c.userData != nil {
-		data, exist = c.userData[key]
-		return
-	}
-	return
+	return c.getUserData(key)
 }
 
 func (c *Context) SetUserData(key string, val interface{}) {
+	c.setUserData(key, val)
+}
+
+func (c *Context) getUserData(key interface{}) (data interface{}, exist bool) {
+	c.userMu.RLock()
+	data, exist = c.userData[key]
+	c.userMu.RUnlock()
+	return
+}
+
+func (c *Context) setUserData(key, val interface{}) {
+	c.userMu.Lock()
 	if c.userData == nil {
-		c.userData = make(map[string]interface{})
+		c.userData = make(map[interface{}]interface{})
 	}
 	c.userData[key] = val
+	c.userMu.Unlock()
+}
+
+// Key is a typed key of the user data, used with Get and Set. Keys are
+// compared by identity, two keys created with the same name are distinct.
+type Key[T any] struct {
+	name string
+}
+
+// NewKey returns a key for values of type T. The name is only used for
+// debugging.
+func NewKey[T any](name string) *Key[T] {
+	return &Key[T]{name: name}
+}
+
+func (k *Key[T]) String() string { return k.name }
+
+// Get returns the user data stored with key.
+func Get[T any](c *Context, key *Key[T]) (val T, exist bool) {
+	data, exist := c.getUserData(key)
+	if exist {
+		// a nil interface value is stored as nil, val is left zero.
+		val, _ = data.(T)
+	}
+	return
+}
+
+// Set stores val in the user data with key.
+func Set[T any](c *Context, key *Key[T], val T) {
+	c.setUserData(key, val)
+}
+
+// =================================
+// ======= context.Context =========
+// =================================
+
+// Deadline returns the deadline of the request context.
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	if c.req == nil {
+		return
+	}
+	return c.req.Context().Deadline()
+}
+
+// Done returns the channel of the request context, which is closed when the
+// client goes away, the request is canceled or its deadline passes.
+func (c *Context) Done() <-chan struct{} {
+	if c.req == nil {
+		return nil
+	}
+	return c.req.Context().Done()
+}
+
+// Err returns the error of the request context.
+func (c *Context) Err() error {
+	if c.req == nil {
+		return nil
+	}
+	return c.req.Context().Err()
+}
+
+// contextKey is the key for which Value returns the Context itself.
+type contextKey struct{}
+
+// Value returns the value of the request context for key. If there is none,
+// the user data with that key is returned.
+func (c *Context) Value(key interface{}) interface{} {
+	if key == (contextKey{}) {
+		return c
+	}
+	if c.req != nil {
+		if val := c.req.Context().Value(key); val != nil {
+			return val
+		}
+	}
+	if val, exist := c.getUserData(key); exist {
+		return val
+	}
+	return nil
+}
+
+// WithContext replaces the context of the request, e.g. to install a
+// deadline observed by the handlers called after the current one:
+//
+//	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second)
+//	defer cancel()
+//	c.WithContext(ctx)
+//	c.Next()
+//
+// ctx must not be derived from c itself, which delegates to the request
+// context and would make the lookups loop forever.
+func (c *Context) WithContext(ctx context.Context) {
+	Assert(ctx.Value(contextKey{}) != c, "the context must be derived from Request().Context(), not from the gweb Context")
+	c.req = c.req.WithContext(ctx)
 }
@@ -0,0 +1,207 @@
+package gweb
+
+import (
+	"context"
+	"github.com/chen-zyc/gweb/binding"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContextAbort(t *testing.T) {
+	s := NewServer()
+	var calls []string
+	s.GET("/abort",
+		func(c *Context) {
+			calls = append(calls, "before")
+			c.Next()
+			calls = append(calls, "after")
+			assert.True(t, c.IsAborted())
+		},
+		func(c *Context) {
+			calls = append(calls, "auth")
+			c.AbortWithStatus(http.StatusUnauthorized)
+			c.Next() // does nothing once aborted
+			calls = append(calls, "auth done")
+		},
+		func(c *Context) {
+			calls = append(calls, "handler")
+		},
+	)
+
+	w := performRequest(s, MethodGet, "/abort")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, []string{"before", "auth", "auth done", "after"}, calls)
+}
+
+func TestContextAbortWithStatusJSON(t *testing.T) {
+	s := NewServer()
+	s.GET("/abort", func(c *Context) {
+		c.AbortWithStatusJSON(http.StatusForbidden, H{"error": "forbidden"})
+	}, func(c *Context) {
+		t.Error("handler must not be called after abort")
+	})
+
+	w := performRequest(s, MethodGet, "/abort")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "{\"error\":\"forbidden\"}\n", w.Body.String())
+}
+
+func TestContextNotAborted(t *testing.T) {
+	s := NewServer()
+	s.GET("/", func(c *Context) {
+		assert.False(t, c.IsAborted())
+	})
+	performRequest(s, MethodGet, "/")
+}
+
+func TestContextBind(t *testing.T) {
+	type query struct {
+		ID   int    `uri:"id"`
+		Name string `form:"name" json:"name"`
+	}
+
+	s := NewServer()
+	s.Handle(MethodPost, "/users/:id", func(c *Context) {
+		var q query
+		assert.NoError(t, c.BindURI(&q))
+		assert.NoError(t, c.Bind(&q))
+		c.JSON(http.StatusOK, q)
+	})
+
+	req, _ := http.NewRequest(MethodPost, "/users/42", strings.NewReader(`{"name":"bob"}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	assert.Equal(t, "{\"ID\":42,\"name\":\"bob\"}\n", w.Body.String())
+}
+
+func TestContextBindValidation(t *testing.T) {
+	type signup struct {
+		Name string `form:"name" binding:"required,min=3"`
+		Code string `form:"code" binding:"invite"`
+	}
+
+	s := NewServer()
+	s.RegisterValidator("invite", func(v reflect.Value, _ string) bool {
+		return v.String() == "gweb"
+	})
+	s.GET("/signup", func(c *Context) {
+		var form signup
+		if err := c.Bind(&form); err != nil {
+			c.Render(err.(binding.Errors))
+			return
+		}
+		c.String(http.StatusOK, "%s", form.Name)
+	})
+
+	w := performRequest(s, MethodGet, "/signup?name=bob&code=gweb")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "bob", w.Body.String())
+
+	w = performRequest(s, MethodGet, "/signup?name=b&code=x")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"field":"Name"`)
+	assert.Contains(t, w.Body.String(), `"tag":"invite"`)
+}
+
+func TestContextClientIP(t *testing.T) {
+	s := NewServer()
+	var ip, fullPath string
+	s.GET("/users/:id", func(c *Context) {
+		ip, fullPath = c.ClientIP(), c.FullPath()
+	})
+
+	req := httptest.NewRequest(MethodGet, "/users/1", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	s.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "10.0.0.1", ip)
+	assert.Equal(t, "/users/:id", fullPath)
+
+	// the headers are ignored by default, any client can set them.
+	req.Header.Set("X-Real-Ip", "10.0.0.3")
+	s.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "10.0.0.1", ip)
+
+	ForwardedByClientIPOption(true)(s)
+	s.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "10.0.0.3", ip)
+
+	req.Header.Set("X-Forwarded-For", " 10.0.0.2, 10.0.0.1")
+	s.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "10.0.0.2", ip)
+}
+
+func TestServeHTTPPanicReleasesContext(t *testing.T) {
+	s := NewServer()
+	var ctx *Context
+	s.GET("/panic", func(c *Context) {
+		ctx = c
+		panic("boom")
+	})
+
+	assert.PanicsWithValue(t, "boom", func() { performRequest(s, MethodGet, "/panic") })
+	assert.Nil(t, ctx.req)
+	assert.Nil(t, ctx.handlers)
+
+	s.PanicHandler = func(c *Context, err interface{}) {
+		c.Status(http.StatusInternalServerError)
+	}
+	w := performRequest(s, MethodGet, "/panic")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Nil(t, ctx.req)
+}
+
+type ctxKey string
+
+func TestContextAsContext(t *testing.T) {
+	s := NewServer()
+	s.GET("/", func(c *Context) {
+		c.SetUserData("user", "bob")
+		ctx, cancel := context.WithTimeout(c.Request().Context(), time.Hour)
+		defer cancel()
+		c.WithContext(context.WithValue(ctx, ctxKey("trace"), "abc"))
+		c.Next()
+	}, func(c *Context) {
+		var ctx context.Context = c
+		deadline, ok := ctx.Deadline()
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), deadline, time.Minute)
+		assert.NoError(t, ctx.Err())
+		assert.Equal(t, "abc", ctx.Value(ctxKey("trace")))
+		assert.Equal(t, "bob", ctx.Value("user"))
+		assert.Nil(t, ctx.Value("missing"))
+	})
+	performRequest(s, MethodGet, "/")
+}
+
+func TestContextCancellation(t *testing.T) {
+	s := NewServer()
+	var err error
+	s.GET("/", func(c *Context) {
+		ctx, cancel := context.WithCancel(c.Request().Context())
+		c.WithContext(ctx)
+		cancel()
+		c.Next()
+	}, func(c *Context) {
+		<-c.Done()
+		err = c.Err()
+	})
+	performRequest(s, MethodGet, "/")
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestContextWithContextDerivedFromItself(t *testing.T) {
+	s := NewServer()
+	s.GET("/", func(c *Context) {
+		ctx, cancel := context.WithCancel(c)
+		defer cancel()
+		assert.Panics(t, func() { c.WithContext(ctx) })
+	})
+	performRequest(s, MethodGet, "/")
+}
@@ -0,0 +1,96 @@
+package gweb
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// ErrCopiedContext is returned when writing a response through a copy of a
+// Context.
+var ErrCopiedContext = errors.New("gweb: can not write the response through a copied context")
+
+// Copy returns a snapshot of the context which can be used after the
+// handler returned, e.g. in a goroutine, while the original context is
+// reused for other requests.
+//
+// The copy holds the request, the params, the route, the handler name and
+// the user data. It is read-only: nothing is written through it, its Writer
+// returns ErrCopiedContext and the helpers like String or JSON record it with
+// Error. The handler chain can not be continued. Its
+// context.Context methods still observe the request context, which is
+// canceled once the request is served.
+func (c *Context) Copy() *Context {
+	cp := &Context{
+		s:               c.s,
+		req:             c.req,
+		fullPath:        c.fullPath,
+		handlerName:     c.HandlerName(),
+		curHandlerIndex: abortIndex,
+	}
+	cp.resp = &copiedWriter{
+		header: c.resp.Header().Clone(),
+		status: c.resp.Status(),
+		size:   c.resp.Size(),
+	}
+	cp.params = make(Params, len(c.params))
+	copy(cp.params, c.params)
+
+	c.userMu.RLock()
+	if c.userData != nil {
+		cp.userData = make(map[interface{}]interface{}, len(c.userData))
+		for k, v := range c.userData {
+			cp.userData[k] = v
+		}
+	}
+	c.userMu.RUnlock()
+	return cp
+}
+
+// HandlerName returns the name of the route handler, e.g.
+// "main.handleGetUsers".
+func (c *Context) HandlerName() string {
+	if c.handlerName != "" {
+		return c.handlerName
+	}
+	if len(c.handlers) == 0 {
+		return ""
+	}
+	return nameOfFunction(c.handlers[len(c.handlers)-1])
+}
+
+// copiedWriter is the ResponseWriter of a copied context. It keeps the
+// status and size of the response when the copy was made and refuses to
+// write.
+type copiedWriter struct {
+	header http.Header
+	status int
+	size   int
+}
+
+var _ ResponseWriter = (*copiedWriter)(nil)
+
+func (w *copiedWriter) Header() http.Header { return w.header }
+
+func (w *copiedWriter) Write([]byte) (int, error) { return 0, ErrCopiedContext }
+
+func (w *copiedWriter) WriteString(string) (int, error) { return 0, ErrCopiedContext }
+
+func (w *copiedWriter) WriteHeader(int) {}
+
+func (w *copiedWriter) WriteHeaderNow() {}
+
+func (w *copiedWriter) Flush() {}
+
+func (w *copiedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, ErrCopiedContext
+}
+
+func (w *copiedWriter) Push(string, *http.PushOptions) error { return ErrCopiedContext }
+
+func (w *copiedWriter) Status() int { return w.status }
+
+func (w *copiedWriter) Size() int { return w.size }
+
+func (w *copiedWriter) Written() bool { return true }
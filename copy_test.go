@@ -0,0 +1,72 @@
+package gweb
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func namedHandler(c *Context) {}
+
+func TestContextCopy(t *testing.T) {
+	s := NewServer()
+	var cp *Context
+	s.GET("/users/:id", func(c *Context) {
+		c.SetUserData("user", "bob")
+		c.Header("X-Test", "1")
+		c.Status(http.StatusAccepted)
+		cp = c.Copy()
+		c.SetUserData("user", "alice")
+	}, namedHandler)
+
+	performRequest(s, MethodGet, "/users/42")
+
+	assert.Equal(t, "42", cp.Param("id"))
+	assert.Equal(t, "/users/:id", cp.FullPath())
+	assert.Equal(t, "github.com/chen-zyc/gweb.namedHandler", cp.HandlerName())
+	assert.Equal(t, "/users/42", cp.Request().URL.Path)
+	user, _ := cp.UserData("user")
+	assert.Equal(t, "bob", user)
+	assert.Equal(t, http.StatusAccepted, cp.Writer().Status())
+	assert.Equal(t, "1", cp.Writer().Header().Get("X-Test"))
+
+	_, err := cp.Writer().Write([]byte("hello"))
+	assert.Equal(t, ErrCopiedContext, err)
+	_, _, err = cp.Writer().Hijack()
+	assert.Equal(t, ErrCopiedContext, err)
+	assert.NotPanics(t, func() {
+		cp.String(http.StatusOK, "hello")
+		cp.JSON(http.StatusOK, map[string]int{"a": 1})
+	})
+	assert.Equal(t, []error{ErrCopiedContext, ErrCopiedContext}, cp.Errors())
+	assert.True(t, cp.IsAborted())
+}
+
+// TestContextCopyPoolReuse reads the copies from goroutines while the
+// original contexts are reused by other requests, run it with -race.
+func TestContextCopyPoolReuse(t *testing.T) {
+	s := NewServer()
+	var wg sync.WaitGroup
+	s.GET("/users/:id", func(c *Context) {
+		c.SetUserData("id", c.Param("id"))
+		cp := c.Copy()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				id, _ := cp.UserData("id")
+				assert.Equal(t, cp.Param("id"), id)
+				assert.Equal(t, "/users/:id", cp.FullPath())
+				assert.NotEmpty(t, cp.HandlerName())
+				assert.Equal(t, "/users/"+cp.Param("id"), cp.Request().URL.Path)
+			}
+		}()
+	})
+
+	for i := 0; i < 100; i++ {
+		performRequest(s, MethodGet, "/users/"+strconv.Itoa(i))
+	}
+	wg.Wait()
+}
@@ -18,7 +18,7 @@ func main() {
 			"name":    name,
 			"message": "welcome to gweb!",
 		})
-	})
+	}).Name("hello")
 	s.GET("/gweb/query", func(c *gweb.Context) {
 		name := c.Query("name")
 		c.String(http.StatusOK, name)
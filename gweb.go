@@ -1,13 +1,21 @@
 package gweb
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/chen-zyc/gweb/binding"
+	"github.com/chen-zyc/gweb/websocket"
 	"html/template"
 	"io"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const (
@@ -22,6 +30,11 @@ const (
 	MethodPatch   = "PATCH"
 )
 
+const (
+	defaultMultipartMemory  = 32 << 20 // 32MB
+	defaultSecureJSONPrefix = "while(1);"
+)
+
 type Handler func(c *Context)
 
 type Handlers []Handler
@@ -79,13 +92,71 @@ type Server struct {
 	PrintLogo bool
 	Logo      string
 
+	// If enabled, the client IP is read from the X-Forwarded-For and
+	// X-Real-IP headers before falling back to the remote address.
+	// Only enable it behind a trusted proxy which sets these headers.
+	ForwardedByClientIP bool
+
+	// If enabled, the route table is printed at startup, one route per line
+	// sorted by host, path and method.
+	PrintRoutes bool
+
+	// Maximum number of bytes of a multipart body kept in memory when it is
+	// parsed, the rest of the files is stored in temporary files.
+	MaxMultipartMemory int64
+
+	// Maximum number of bytes of a request body, reading more fails with
+	// *http.MaxBytesError. Zero means no limit.
+	MaxBodySize int64
+
+	// Prefix written before the body by Context.SecureJSON.
+	SecureJSONPrefix string
+
+	// Upgrader used by the routes registered with RouterGroup.WebSocket.
+	WebSocketUpgrader websocket.Upgrader
+
+	// If enabled, RouterGroup.Handle records the routes which can not be
+	// registered instead of panicking, and Validate reports all of them at
+	// once. Serve refuses to start while there is any.
+	DeferRouteErrors bool
+
+	// Maximum duration for each OnStart or OnShutdown hook.
+	// Zero means the hooks are not bounded by a timeout.
+	HookTimeout time.Duration
+
+	// Maximum duration Run waits for in-flight requests to finish after
+	// receiving one of the shutdown signals. Zero means wait forever.
+	ShutdownTimeout time.Duration
+
 	htmlTemplate *template.Template
+	funcMap      template.FuncMap
+	validator    *binding.Validator
 
 	name    string
 	address string
 
-	trees   map[string]Router
+	// table holds the *routeTable, replaced as a whole when the routes
+	// change so that requests can be served meanwhile.
+	table        atomic.Value
+	tablePending int32      // set while pending has trees to publish
+	routesMu     sync.Mutex // guards the fields below and the changes of table
+	routes       []*Route
+	pending      map[string]*pendingTree // by host and method
+	namedRoutes  map[string]*Route
+	routeErrors  []*RouteError
+	hostShapes   map[string]string // pattern given to Host by shape
+
 	ctxPool sync.Pool
+
+	mu              sync.Mutex
+	httpServer      *http.Server
+	shuttingDown    bool // set by Shutdown, Serve refuses to start afterwards
+	shutdownSignals []os.Signal
+	startHooks      []Hook
+	shutdownHooks   []Hook
+	shutdownOnce    sync.Once
+	shutdownErr     error
+	shutdownDone    chan struct{}
 }
 
 var _ http.Handler = (*Server)(nil)
@@ -96,63 +167,203 @@ func NewServer() *Server {
 		RedirectFixedPath:      true,
 		HandleOPTIONS:          true,
 		HandleMethodNotAllowed: true,
+		MaxMultipartMemory:     defaultMultipartMemory,
 		PrintLogo:              true,
-		trees:                  make(map[string]Router, 9),
+		SecureJSONPrefix:       defaultSecureJSONPrefix,
+		namedRoutes:            make(map[string]*Route),
+		hostShapes:             make(map[string]string),
+		shutdownDone:           make(chan struct{}),
+		validator:              binding.NewValidator(),
 	}
 	s.RouterGroup = NewGroup(s, "/")
 	s.ctxPool.New = func() interface{} { return &Context{} }
 	return s
 }
 
+// Run listens on the TCP network address and serves requests until the
+// server is shut down. If shutdown signals were configured with
+// GracefulShutdownOption, Run shuts the server down gracefully when one of
+// them is received and returns after the shutdown has completed.
 func (s *Server) Run(address string, opts ...Option) error {
 	for _, opt := range opts {
 		opt(s)
 	}
 	s.address = address
 
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts incoming connections on the listener l and serves requests
+// until the server is shut down. The OnStart hooks are run before the first
+// connection is accepted; if one of them fails, l is closed and the error is
+// returned.
+func (s *Server) Serve(l net.Listener) error {
+	if s.address == "" {
+		s.address = l.Addr().String()
+	}
+
+	if err := s.Validate(); err != nil {
+		l.Close()
+		return err
+	}
+
+	if err := runHooks(context.Background(), s.startHooks, s.HookTimeout); err != nil {
+		l.Close()
+		return err
+	}
+
+	s.mu.Lock()
+	if s.shuttingDown {
+		s.mu.Unlock()
+		l.Close()
+		return http.ErrServerClosed
+	}
+	s.httpServer = &http.Server{Handler: s}
+	srv := s.httpServer
+	s.mu.Unlock()
+
+	if len(s.shutdownSignals) > 0 {
+		stop := s.handleSignals()
+		defer stop()
+	}
+
 	s.printLogo(os.Stdout)
 	s.printInfo(os.Stdout)
-
-	err := http.ListenAndServe(address, s)
+	s.printRoutes(os.Stdout)
+
+	err := srv.Serve(l)
+	if errors.Is(err, http.ErrServerClosed) {
+		// Serve returns immediately once Shutdown is called, wait for the
+		// in-flight requests and the shutdown hooks.
+		<-s.shutdownDone
+		return s.shutdownErr
+	}
 	return err
 }
 
+// Shutdown gracefully shuts down the server: it stops accepting new
+// connections, waits for the in-flight requests to finish and then runs the
+// OnShutdown hooks in the order they were registered. If ctx expires before
+// the requests are drained, the remaining connections are closed and the
+// context's error is returned.
+// Calling Shutdown more than once returns the result of the first call.
+// Serve returns http.ErrServerClosed once Shutdown has been called.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shutdownOnce.Do(func() {
+		defer close(s.shutdownDone)
+
+		s.mu.Lock()
+		s.shuttingDown = true
+		srv := s.httpServer
+		s.mu.Unlock()
+
+		if srv != nil {
+			if err := srv.Shutdown(ctx); err != nil {
+				srv.Close()
+				s.shutdownErr = err
+			}
+		}
+		// the hooks get their own timeout, ctx may already be exhausted by draining.
+		if err := runHooks(context.Background(), s.shutdownHooks, s.HookTimeout); err != nil && s.shutdownErr == nil {
+			s.shutdownErr = err
+		}
+	})
+	<-s.shutdownDone
+	return s.shutdownErr
+}
+
+// RegisterValidator adds a validation rule usable in the `binding` tags of
+// the structs decoded by Context.Bind*.
+func (s *Server) RegisterValidator(tag string, fn binding.ValidatorFunc) {
+	s.validator.Register(tag, fn)
+}
+
+// SetHTMLTemplate sets the template used by Context.HTML. The functions of
+// FuncMap are added to t, templates using them must be parsed with
+// t.Funcs(s.FuncMap()).
 func (s *Server) SetHTMLTemplate(t *template.Template) {
-	s.htmlTemplate = t
+	s.htmlTemplate = t.Funcs(s.FuncMap())
 }
 
 func (s *Server) LoadHTMLFiles(files ...string) {
-	s.SetHTMLTemplate(template.Must(template.ParseFiles(files...)))
+	Assert(len(files) > 0, "no files named in LoadHTMLFiles")
+	t := template.New(filepath.Base(files[0])).Funcs(s.FuncMap())
+	s.SetHTMLTemplate(template.Must(t.ParseFiles(files...)))
 }
 
 func (s *Server) LoadHTMLGlob(pattern string) {
-	s.SetHTMLTemplate(template.Must(template.ParseGlob(pattern)))
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		panic(err)
+	}
+	Assert(len(files) > 0, fmt.Sprintf("pattern matches no files: '%s'", pattern))
+	t := template.New(filepath.Base(files[0])).Funcs(s.FuncMap())
+	s.SetHTMLTemplate(template.Must(t.ParseGlob(pattern)))
+}
+
+// SetFuncMap adds functions to the templates loaded by LoadHTMLFiles and
+// LoadHTMLGlob. It must be called before the templates are loaded.
+func (s *Server) SetFuncMap(funcMap template.FuncMap) {
+	if s.funcMap == nil {
+		s.funcMap = make(template.FuncMap, len(funcMap))
+	}
+	for name, fn := range funcMap {
+		s.funcMap[name] = fn
+	}
+}
+
+// FuncMap returns the functions available in the HTML templates: the ones
+// added with SetFuncMap and "url", which builds the URL of a named route
+// like Server.URL, e.g. {{ url "hello" "name" .Name }}.
+func (s *Server) FuncMap() template.FuncMap {
+	funcMap := template.FuncMap{"url": s.URL}
+	for name, fn := range s.funcMap {
+		funcMap[name] = fn
+	}
+	return funcMap
 }
 
 func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if s.MaxBodySize > 0 && req.Body != nil {
+		req.Body = http.MaxBytesReader(w, req.Body, s.MaxBodySize)
+	}
+
 	ctx := s.getContext()
 	ctx.reset(req, w)
+	ctx.s = s
+	// deferred so that the context goes back to the pool even if a handler
+	// panics and the panic is left to net/http.
+	defer s.putContext(ctx)
 
 	if s.PanicHandler != nil {
 		defer func() {
 			if err := recover(); err != nil {
 				s.PanicHandler(ctx, err)
+				ctx.resp.WriteHeaderNow()
 			}
 		}()
 	}
 	s.handleRequest(ctx)
-	s.putContext(ctx)
+	ctx.resp.WriteHeaderNow()
 }
 
 func (s *Server) handleRequest(ctx *Context) {
 	req := ctx.req
 	method, path := req.Method, req.URL.Path
 
-	if router := s.trees[method]; router != nil {
-		handlers, params, tsr := router.Find(path)
+	trees, hostParams := s.loadTable().match(req.Host)
+	if router := trees[method]; router != nil {
+		handlers, params, fullPath, tsr := router.Find(path)
 		if handlers != nil {
-			ctx.s = s
+			if hostParams != nil {
+				params = append(hostParams, params...)
+			}
 			ctx.params = params
+			ctx.fullPath = fullPath
 			ctx.handlers = handlers
 			ctx.Next()
 			return
@@ -187,7 +398,7 @@ func (s *Server) handleRequest(ctx *Context) {
 
 	if method == MethodOptions {
 		if s.HandleOPTIONS {
-			if allow := s.allowed(path, method); allow != "" {
+			if allow := s.allowed(trees, path, method); allow != "" {
 				ctx.resp.Header().Set("Allow", allow)
 				return
 			}
@@ -195,7 +406,7 @@ func (s *Server) handleRequest(ctx *Context) {
 	} else {
 		// handle 405
 		if s.HandleMethodNotAllowed {
-			if allow := s.allowed(path, method); allow != "" {
+			if allow := s.allowed(trees, path, method); allow != "" {
 				ctx.resp.Header().Set("Allow", allow)
 				s.methodNotAllowed(ctx)
 				return
@@ -209,24 +420,27 @@ func (s *Server) handleRequest(ctx *Context) {
 
 func (s *Server) getContext() *Context { return s.ctxPool.Get().(*Context) }
 
-func (s *Server) putContext(c *Context) { s.ctxPool.Put(c) }
+func (s *Server) putContext(c *Context) {
+	c.release()
+	s.ctxPool.Put(c)
+}
 
-func (s *Server) allowed(path, method string) (allow string) {
-	allowSlice := make([]string, 0, len(s.trees)+1)
+func (s *Server) allowed(trees map[string]Router, path, method string) (allow string) {
+	allowSlice := make([]string, 0, len(trees)+1)
 	if path == "*" { // server-wide
-		for m := range s.trees {
+		for m := range trees {
 			if m == MethodOptions {
 				continue
 			}
 			allowSlice = append(allowSlice, m)
 		}
 	} else { // specific path
-		for m := range s.trees {
+		for m, router := range trees {
 			// Skip the requested method - we already tried this one
 			if method == m || m == MethodOptions {
 				continue
 			}
-			handler, _, _ := s.trees[m].Find(path)
+			handler, _, _, _ := router.Find(path)
 			if handler != nil {
 				allowSlice = append(allowSlice, m)
 			}
@@ -239,20 +453,20 @@ func (s *Server) allowed(path, method string) (allow string) {
 	return
 }
 
+// methodNotAllowed and notFound leave the server unchanged, they can be
+// called by concurrent requests.
 func (s *Server) methodNotAllowed(ctx *Context) {
 	if s.MethodNotAllowed == nil {
-		s.MethodNotAllowed = func(c *Context) {
-			http.Error(c.resp, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
-		}
+		http.Error(ctx.resp, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
 	}
 	s.MethodNotAllowed(ctx)
 }
 
 func (s *Server) notFound(ctx *Context) {
 	if s.NotFound == nil {
-		s.NotFound = func(c *Context) {
-			http.Error(c.resp, http.StatusText(http.StatusNotFound), http.StatusNotFound)
-		}
+		http.Error(ctx.resp, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
 	}
 	s.NotFound(ctx)
 }
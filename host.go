@@ -0,0 +1,119 @@
+package gweb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Host returns a group whose routes only match the requests for the host
+// pattern, e.g. "api.example.com" or ":tenant.example.com". A label
+// starting with ':' matches any label and is available from Context.Param,
+// before the params of the path. The pattern has no port, the port of the
+// request is ignored. Two patterns can not differ by the names of their
+// params only, e.g. ":a.example.com" and ":b.example.com".
+//
+// The hosts without params are tried first, then the others in the order
+// they were registered. A request whose host matches no pattern is routed
+// with the routes of the server, a request whose host matches a pattern is
+// only routed with the routes of that host. The group starts with the global
+// handlers of the server.
+func (s *Server) Host(pattern string) *RouterGroup {
+	h := parseHostPattern(pattern)
+	Assert(h.pattern != "", "host pattern can not be empty")
+
+	s.routesMu.Lock()
+	shape := h.shape()
+	old, exist := s.hostShapes[shape]
+	if !exist {
+		s.hostShapes[shape] = h.pattern
+	}
+	s.routesMu.Unlock()
+	if exist && old != h.pattern {
+		panic(fmt.Sprintf("host pattern '%s' conflicts with '%s'", pattern, old))
+	}
+
+	g := s.Group("/")
+	g.host = h.pattern
+	return g
+}
+
+// hostPattern is a host name whose labels can be params.
+type hostPattern struct {
+	pattern string
+	labels  []string
+	params  int
+}
+
+// parseHostPattern parses the pattern given to Server.Host. It panics if a
+// label is empty or the pattern has a port.
+func parseHostPattern(pattern string) *hostPattern {
+	h := &hostPattern{pattern: normalizeHost(pattern)}
+	if h.pattern == "" {
+		return h
+	}
+	h.labels = strings.Split(h.pattern, ".")
+	for _, label := range h.labels {
+		if label == "" || label == ":" {
+			panic(fmt.Sprintf("empty label in host pattern '%s'", pattern))
+		}
+		if strings.IndexByte(label[1:], ':') >= 0 {
+			panic(fmt.Sprintf("host pattern '%s' can not have a port", pattern))
+		}
+		if label[0] == ':' {
+			h.params++
+		}
+	}
+	return h
+}
+
+// shape returns the pattern with the names of its params removed, the
+// patterns of the same shape match the same hosts.
+func (h *hostPattern) shape() string {
+	labels := make([]string, len(h.labels))
+	for i, label := range h.labels {
+		if label[0] == ':' {
+			label = ":"
+		}
+		labels[i] = label
+	}
+	return strings.Join(labels, ".")
+}
+
+// match returns the params of the host if its labels match the pattern.
+func (h *hostPattern) match(labels []string) (Params, bool) {
+	if len(labels) != len(h.labels) {
+		return nil, false
+	}
+	for i, label := range h.labels {
+		if label[0] != ':' && label != labels[i] {
+			return nil, false
+		}
+	}
+	if h.params == 0 {
+		return nil, true
+	}
+	params := make(Params, 0, h.params)
+	for i, label := range h.labels {
+		if label[0] == ':' {
+			params = append(params, Param{Key: label[1:], Value: labels[i]})
+		}
+	}
+	return params, true
+}
+
+// splitHost returns the labels of the host of a request, without its port.
+func splitHost(host string) []string {
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.Contains(host[i:], "]") {
+		host = host[:i]
+	}
+	host = normalizeHost(host)
+	if host == "" {
+		return nil
+	}
+	return strings.Split(host, ".")
+}
+
+// normalizeHost lowercases host and removes its trailing dot.
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
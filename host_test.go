@@ -0,0 +1,92 @@
+package gweb
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func performHostRequest(s *Server, method, host, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Host = host
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	return w
+}
+
+func TestHostRouting(t *testing.T) {
+	s := NewServer()
+	var order []string
+	s.Global(func(c *Context) { order = append(order, "global") })
+	s.GET("/users/:id", paramsHandler("default"))
+
+	tenant := s.Host(":tenant.example.com")
+	tenant.GET("/users/:id", paramsHandler("tenant"))
+	api := s.Host("API.example.com.")
+	api.Group("/v1").GET("/users/:id", paramsHandler("api"))
+	s.Host(":region.:tenant.example.com").GET("/", paramsHandler("region"))
+
+	tests := []struct {
+		host, path string
+		code       int
+		body       string
+	}{
+		{"example.com", "/users/1", http.StatusOK, "default [{id 1}]"},
+		{"acme.example.com", "/users/1", http.StatusOK, "tenant [{tenant acme} {id 1}]"},
+		{"ACME.example.com:8080", "/users/1", http.StatusOK, "tenant [{tenant acme} {id 1}]"},
+		// the host without params is tried first.
+		{"api.example.com", "/v1/users/1", http.StatusOK, "api [{id 1}]"},
+		{"api.example.com", "/users/1", http.StatusNotFound, ""},
+		{"eu.acme.example.com", "/", http.StatusOK, "region [{region eu} {tenant acme}]"},
+		{"a.b.c.example.com", "/users/1", http.StatusOK, "default [{id 1}]"},
+		{"[::1]:8080", "/users/1", http.StatusOK, "default [{id 1}]"},
+	}
+	for _, tt := range tests {
+		w := performHostRequest(s, MethodGet, tt.host, tt.path)
+		assert.Equal(t, tt.code, w.Code, tt.host+tt.path)
+		if tt.body != "" {
+			assert.Equal(t, tt.body, w.Body.String(), tt.host+tt.path)
+		}
+	}
+	assert.Equal(t, []string{"global", "global"}, order[:2])
+
+	// the redirects and 405 use the trees of the host.
+	w := performHostRequest(s, MethodGet, "api.example.com", "/V1/users/1")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/v1/users/1", w.Header().Get("Location"))
+	w = performHostRequest(s, MethodPost, "acme.example.com", "/users/1")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	w = performHostRequest(s, MethodPost, "api.example.com", "/users/1")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	routes := s.Routes()
+	assert.Len(t, routes, 4)
+	assert.Equal(t, "", routes[0].Host)
+	assert.Equal(t, ":region.:tenant.example.com", routes[1].Host)
+	assert.Equal(t, "api.example.com", routes[3].Host)
+	assert.Equal(t, "/v1/users/:id", routes[3].Path)
+
+	// the same path can be registered differently on each host.
+	_, err := tenant.TryHandle(MethodGet, "/users/:name/x", emptyHandler)
+	assert.Contains(t, err.Error(), "GET :tenant.example.com/users/:name/x")
+	assert.Contains(t, err.Error(), "conflicts with GET :tenant.example.com/users/:id")
+	s.Host("other.example.com").GET("/users/:name/x", emptyHandler)
+
+	assert.True(t, s.RemoveHostRoute("API.example.com", MethodGet, "/v1/users/:id"))
+	assert.False(t, s.RemoveRoute(MethodGet, "/v1/users/:id"))
+	// the host without routes is left, the next pattern matches.
+	w = performHostRequest(s, MethodGet, "api.example.com", "/users/1")
+	assert.Equal(t, "tenant [{tenant api} {id 1}]", w.Body.String())
+
+	assert.Panics(t, func() { s.Host("") })
+	assert.Panics(t, func() { s.Host("a..example.com") })
+	assert.Panics(t, func() { s.Host(":.example.com") })
+	assert.PanicsWithValue(t, "host pattern 'api.example.com:8080' can not have a port", func() {
+		s.Host("api.example.com:8080")
+	})
+	assert.PanicsWithValue(t, "host pattern ':name.example.com' conflicts with ':tenant.example.com'", func() {
+		s.Host(":name.example.com")
+	})
+	assert.NotPanics(t, func() { s.Host(":tenant.Example.com") })
+}
@@ -0,0 +1,88 @@
+package gweb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Hook is a function run when the server starts or shuts down.
+// The context is canceled when Server.HookTimeout elapses.
+type Hook func(ctx context.Context) error
+
+// OnStart registers hooks which are run in order before the server starts
+// accepting connections. The first error aborts the start.
+func (s *Server) OnStart(hooks ...Hook) {
+	s.startHooks = append(s.startHooks, hooks...)
+}
+
+// OnShutdown registers hooks which are run in order after the in-flight
+// requests have been drained by Shutdown. All hooks are run, the first error
+// is returned by Shutdown.
+func (s *Server) OnShutdown(hooks ...Hook) {
+	s.shutdownHooks = append(s.shutdownHooks, hooks...)
+}
+
+// runHooks runs the hooks one by one. A hook which does not return within
+// timeout is abandoned and reported as an error. Every hook is run even if a
+// previous one failed, the first error is returned.
+func runHooks(ctx context.Context, hooks []Hook, timeout time.Duration) (err error) {
+	for i, hook := range hooks {
+		if e := runHook(ctx, hook, timeout); e != nil && err == nil {
+			err = fmt.Errorf("hook #%d: %w", i, e)
+		}
+	}
+	return
+}
+
+func runHook(ctx context.Context, hook Hook, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- hook(ctx) }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// handleSignals shuts the server down when one of the configured signals is
+// received. The returned function stops listening for the signals.
+func (s *Server) handleSignals() (stop func()) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, s.shutdownSignals...)
+	quit := make(chan struct{})
+
+	go func() {
+		select {
+		case <-sigCh:
+			ctx := context.Background()
+			if s.ShutdownTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, s.ShutdownTimeout)
+				defer cancel()
+			}
+			s.Shutdown(ctx)
+		case <-quit:
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigCh)
+		close(quit)
+	}
+}
+
+// defaultShutdownSignals are used by GracefulShutdownOption when no signal
+// is given.
+var defaultShutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
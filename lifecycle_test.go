@@ -0,0 +1,160 @@
+package gweb
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func startServer(t *testing.T, s *Server) (addr string, served chan error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	s.PrintLogo = false
+	served = make(chan error, 1)
+	go func() { served <- s.Serve(l) }()
+	return l.Addr().String(), served
+}
+
+func TestShutdownDrainsInFlightRequests(t *testing.T) {
+	s := NewServer()
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	s.GET("/slow", func(c *Context) {
+		close(entered)
+		<-release
+		c.String(http.StatusOK, "done")
+	})
+	addr, served := startServer(t, s)
+
+	type result struct {
+		body string
+		err  error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			resCh <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		resCh <- result{string(b), err}
+	}()
+	<-entered
+
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- s.Shutdown(context.Background()) }()
+
+	// new connections are refused once the shutdown began.
+	assert.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+		}
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+
+	select {
+	case <-shutdownErr:
+		t.Fatal("Shutdown returned before the in-flight request finished")
+	default:
+	}
+
+	close(release)
+	res := <-resCh
+	assert.NoError(t, res.err)
+	assert.Equal(t, "done", res.body)
+	assert.NoError(t, <-shutdownErr)
+	assert.NoError(t, <-served)
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	s := NewServer()
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	s.GET("/slow", func(c *Context) {
+		close(entered)
+		<-release
+	})
+	addr, served := startServer(t, s)
+
+	go http.Get("http://" + addr + "/slow")
+	<-entered
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, s.Shutdown(ctx))
+	assert.Equal(t, context.DeadlineExceeded, <-served)
+}
+
+func TestShutdownBeforeServe(t *testing.T) {
+	s := NewServer()
+	assert.NoError(t, s.Shutdown(context.Background()))
+
+	addr, served := startServer(t, s)
+	select {
+	case err := <-served:
+		assert.Equal(t, http.ErrServerClosed, err)
+	case <-time.After(time.Second):
+		t.Fatal("Serve started after Shutdown")
+	}
+	// the listener is closed.
+	_, err := net.Dial("tcp", addr)
+	assert.Error(t, err)
+}
+
+func TestLifecycleHooksOrder(t *testing.T) {
+	s := NewServer()
+	var calls []string
+	hook := func(name string) Hook {
+		return func(ctx context.Context) error {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+	s.OnStart(hook("start1"), hook("start2"))
+	s.OnShutdown(hook("shutdown1"), hook("shutdown2"))
+
+	_, served := startServer(t, s)
+	assert.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.httpServer != nil
+	}, time.Second, time.Millisecond)
+
+	assert.NoError(t, s.Shutdown(context.Background()))
+	assert.NoError(t, <-served)
+	assert.Equal(t, []string{"start1", "start2", "shutdown1", "shutdown2"}, calls)
+}
+
+func TestLifecycleHookErrors(t *testing.T) {
+	s := NewServer()
+	errStart := errors.New("start failed")
+	s.OnStart(func(ctx context.Context) error { return errStart })
+	_, served := startServer(t, s)
+	assert.True(t, errors.Is(<-served, errStart))
+
+	s = NewServer()
+	s.HookTimeout = 20 * time.Millisecond
+	called := false
+	s.OnShutdown(
+		func(ctx context.Context) error {
+			<-ctx.Done()
+			time.Sleep(time.Second) // ignores the timeout
+			return nil
+		},
+		func(ctx context.Context) error {
+			called = true
+			return nil
+		},
+	)
+	assert.True(t, errors.Is(s.Shutdown(context.Background()), context.DeadlineExceeded))
+	assert.True(t, called)
+}
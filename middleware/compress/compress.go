@@ -0,0 +1,259 @@
+// Package compress provides a middleware compressing the responses with
+// gzip, deflate or zstd, as negotiated with the Accept-Encoding header.
+package compress
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"github.com/chen-zyc/gweb"
+	"github.com/chen-zyc/gweb/websocket"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Supported encodings.
+const (
+	Gzip    = "gzip"
+	Deflate = "deflate"
+	Zstd    = "zstd"
+)
+
+// DefaultMinSize is the minimum size of the compressed bodies when
+// Config.MinSize is zero.
+const DefaultMinSize = 1024
+
+var (
+	// DefaultContentTypes are the types compressed when
+	// Config.ContentTypes is empty.
+	DefaultContentTypes = []string{
+		"text/",
+		"application/json",
+		"application/javascript",
+		"application/xml",
+		"application/yaml",
+		"application/x-yaml",
+		"application/toml",
+		"application/wasm",
+		"image/svg+xml",
+	}
+	// DefaultExcludedContentTypes are already compressed types, never
+	// compressed when Config.ExcludedContentTypes is empty.
+	DefaultExcludedContentTypes = []string{
+		"image/png",
+		"image/jpeg",
+		"image/gif",
+		"image/webp",
+		"image/avif",
+		"video/",
+		"audio/",
+		"font/woff",
+		"font/woff2",
+		"application/zip",
+		"application/gzip",
+		"application/x-gzip",
+		"application/zstd",
+		"application/x-7z-compressed",
+		"application/x-rar-compressed",
+	}
+)
+
+type Config struct {
+	// Encodings lists the encodings used in order of preference, which
+	// breaks the ties between the encodings the client accepts equally.
+	// Zstd, Gzip and Deflate by default.
+	Encodings []string
+	// Level is the compression level, from 1 (best speed) to 9 (best
+	// compression). It is mapped to the closest zstd level. Zero means the
+	// default level of each encoding. New panics if the level is out of
+	// range.
+	Level int
+	// MinSize is the minimum size of the body to compress, smaller bodies
+	// are sent as is. DefaultMinSize when zero, no minimum when negative.
+	MinSize int
+	// ContentTypes lists the media types to compress. An entry ending with
+	// '/' matches every subtype, e.g. "text/". DefaultContentTypes when
+	// empty.
+	ContentTypes []string
+	// ExcludedContentTypes lists the media types never compressed, with the
+	// same syntax. DefaultExcludedContentTypes when empty. Server-Sent
+	// Events are always excluded.
+	ExcludedContentTypes []string
+}
+
+// Default returns the middleware with the default configuration.
+func Default() gweb.Handler {
+	return New(Config{})
+}
+
+// New returns the middleware compressing the bodies of the responses. The
+// decision is taken when the first MinSize bytes are written, the handler
+// returns or flushes: the response is compressed if the status allows a
+// body, it has no Content-Encoding yet, its Content-Type is allowed and the
+// client accepts one of the encodings. Eligible responses get a
+// "Vary: Accept-Encoding" header, compressed ones lose their Content-Length
+// and their strong ETag is made weak.
+//
+// WebSocket upgrades and Server-Sent Events are not compressed.
+func New(cfg Config) gweb.Handler {
+	if cfg.Level < 0 || cfg.Level > flate.BestCompression {
+		panic("compress: invalid level " + strconv.Itoa(cfg.Level) + ", it must be between 1 and 9")
+	}
+	encodings := cfg.Encodings
+	if len(encodings) == 0 {
+		encodings = []string{Zstd, Gzip, Deflate}
+	}
+	minSize := cfg.MinSize
+	if minSize == 0 {
+		minSize = DefaultMinSize
+	}
+	contentTypes := cfg.ContentTypes
+	if len(contentTypes) == 0 {
+		contentTypes = DefaultContentTypes
+	}
+	excluded := cfg.ExcludedContentTypes
+	if len(excluded) == 0 {
+		excluded = DefaultExcludedContentTypes
+	}
+	pools := make(map[string]*sync.Pool, len(encodings))
+	for _, enc := range encodings {
+		pools[enc] = newPool(enc, cfg.Level)
+	}
+
+	return func(c *gweb.Context) {
+		req := c.Request()
+		if websocket.IsWebSocketUpgrade(req) {
+			c.Next()
+			return
+		}
+
+		w := c.Writer()
+		cw := &compressWriter{
+			ResponseWriter: w,
+			status:         http.StatusOK,
+			minSize:        minSize,
+			encoding:       negotiate(req.Header.Values("Accept-Encoding"), encodings),
+			pools:          pools,
+			eligible: func(contentType string) bool {
+				return matchType(contentType, contentTypes) && !matchType(contentType, excluded)
+			},
+		}
+		c.SetWriter(cw)
+		returned := false
+		defer func() {
+			c.SetWriter(w)
+			if returned {
+				cw.close()
+			} else {
+				// a handler panicked: the buffered body is dropped so that
+				// a recovery middleware can still write an error.
+				cw.release()
+			}
+		}()
+		c.Next()
+		returned = true
+	}
+}
+
+// encoder is implemented by the writers of the three encodings.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+type zstdEncoder struct{ *zstd.Encoder }
+
+func (e zstdEncoder) Reset(w io.Writer) { e.Encoder.Reset(w) }
+
+func newPool(encoding string, level int) *sync.Pool {
+	if level == 0 {
+		level = flate.DefaultCompression
+	}
+	var create func() encoder
+	switch encoding {
+	case Gzip:
+		create = func() encoder {
+			gw, _ := gzip.NewWriterLevel(nil, level)
+			return gw
+		}
+	case Deflate:
+		create = func() encoder {
+			fw, _ := flate.NewWriter(nil, level)
+			return fw
+		}
+	case Zstd:
+		zlevel := zstd.SpeedDefault
+		if level > 0 {
+			zlevel = zstd.EncoderLevelFromZstd(level)
+		}
+		create = func() encoder {
+			zw, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zlevel),
+				zstd.WithEncoderConcurrency(1), zstd.WithLowerEncoderMem(true))
+			return zstdEncoder{zw}
+		}
+	default:
+		panic("compress: unsupported encoding " + encoding)
+	}
+	return &sync.Pool{New: func() interface{} { return create() }}
+}
+
+// negotiate returns the encoding with the highest quality in the
+// Accept-Encoding header, "" if none is acceptable.
+func negotiate(header []string, encodings []string) string {
+	qualities := make(map[string]float64)
+	for _, v := range header {
+		for _, part := range strings.Split(v, ",") {
+			params := strings.Split(part, ";")
+			name := strings.ToLower(strings.TrimSpace(params[0]))
+			if name == "" {
+				continue
+			}
+			q := 1.0
+			for _, param := range params[1:] {
+				k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if strings.TrimSpace(k) == "q" {
+					if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+						q = f
+					} else {
+						q = 0
+					}
+				}
+			}
+			qualities[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range encodings {
+		q, ok := qualities[enc]
+		if !ok {
+			q = qualities["*"]
+		}
+		if q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// matchType reports whether the media type of contentType is in the list.
+func matchType(contentType string, list []string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(contentType))
+	if i := strings.IndexByte(mediaType, ';'); i >= 0 {
+		mediaType = strings.TrimSpace(mediaType[:i])
+	}
+	for _, t := range list {
+		if strings.HasSuffix(t, "/") {
+			if strings.HasPrefix(mediaType, t) {
+				return true
+			}
+		} else if mediaType == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,207 @@
+package compress
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"github.com/chen-zyc/gweb"
+	"github.com/chen-zyc/gweb/middleware/recovery"
+	"github.com/chen-zyc/gweb/websocket"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var large = strings.Repeat(`{"name":"gopher"},`, 200)
+
+func largeHandler(c *gweb.Context) {
+	c.Header("Content-Length", strconv.Itoa(len(large)))
+	c.Header("ETag", `"v1"`)
+	c.Header("Content-Type", "application/json")
+	c.String(http.StatusOK, "%s", large)
+}
+
+func pngHandler(c *gweb.Context) {
+	c.Header("Content-Type", "image/png")
+	c.Writer().WriteString(large)
+}
+
+func smallHandler(c *gweb.Context) {
+	c.JSON(http.StatusOK, gweb.H{"ok": true})
+}
+
+func perform(s *gweb.Server, path, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	return w
+}
+
+func decode(t *testing.T, encoding string, body []byte) string {
+	var r io.Reader
+	switch encoding {
+	case Gzip:
+		gr, err := gzip.NewReader(bytes.NewReader(body))
+		assert.NoError(t, err)
+		r = gr
+	case Deflate:
+		r = flate.NewReader(bytes.NewReader(body))
+	case Zstd:
+		zr, err := zstd.NewReader(bytes.NewReader(body))
+		assert.NoError(t, err)
+		defer zr.Close()
+		r = zr
+	default:
+		return string(body)
+	}
+	data, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	return string(data)
+}
+
+func TestEncodings(t *testing.T) {
+	s := gweb.NewServer()
+	s.Global(New(Config{}))
+	s.GET("/large", largeHandler)
+	for accept, encoding := range map[string]string{
+		"gzip":                            Gzip,
+		"deflate":                         Deflate,
+		"zstd":                            Zstd,
+		"gzip, deflate, br, zstd":         Zstd,
+		"gzip;q=1, zstd;q=0.5":            Gzip,
+		"*":                               Zstd,
+		"*;q=0.1, deflate":                Deflate,
+		"zstd;q=0, gzip;q=0, *;q=0.5":     Deflate,
+		"br":                              "",
+		"identity":                        "",
+		"gzip;q=0, deflate;q=0, zstd;q=0": "",
+	} {
+		w := perform(s, "/large", accept)
+		assert.Equal(t, http.StatusOK, w.Code, accept)
+		assert.Equal(t, encoding, w.Header().Get("Content-Encoding"), accept)
+		assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"), accept)
+		assert.Equal(t, large, decode(t, encoding, w.Body.Bytes()), accept)
+		if encoding != "" {
+			assert.Empty(t, w.Header().Get("Content-Length"), accept)
+			assert.Equal(t, `W/"v1"`, w.Header().Get("ETag"), accept)
+			assert.Less(t, w.Body.Len(), len(large), accept)
+		} else {
+			assert.Equal(t, strconv.Itoa(len(large)), w.Header().Get("Content-Length"), accept)
+			assert.Equal(t, `"v1"`, w.Header().Get("ETag"), accept)
+		}
+	}
+
+	s = gweb.NewServer()
+	s.Global(New(Config{Encodings: []string{Gzip}, Level: gzip.BestCompression}))
+	s.GET("/large", largeHandler)
+	w := perform(s, "/large", "zstd, gzip")
+	assert.Equal(t, Gzip, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, large, decode(t, Gzip, w.Body.Bytes()))
+
+	assert.PanicsWithValue(t, "compress: invalid level 11, it must be between 1 and 9", func() {
+		New(Config{Level: 11})
+	})
+	assert.Panics(t, func() { New(Config{Level: -1}) })
+}
+
+func TestSkippedResponses(t *testing.T) {
+	s := gweb.NewServer()
+	s.Global(New(Config{}))
+	s.GET("/small", smallHandler)
+	s.GET("/png", pngHandler)
+	s.GET("/sniffed", func(c *gweb.Context) {
+		c.Writer().WriteString("<html><body>" + large + "</body></html>")
+	})
+	s.GET("/encoded", func(c *gweb.Context) {
+		c.Header("Content-Encoding", "br")
+		c.String(http.StatusOK, "%s", large)
+	})
+	s.GET("/empty", func(c *gweb.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	s.GET("/events", func(c *gweb.Context) {
+		c.SSEvent("tick", large)
+		c.Writer().Flush()
+	})
+
+	w := perform(s, "/small", "gzip")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	assert.Equal(t, "{\"ok\":true}\n", w.Body.String())
+
+	w = perform(s, "/png", "gzip")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Empty(t, w.Header().Get("Vary"))
+	assert.Equal(t, large, w.Body.String())
+
+	w = perform(s, "/encoded", "gzip")
+	assert.Equal(t, "br", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, large, w.Body.String())
+
+	w = perform(s, "/empty", "gzip")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+
+	w = perform(s, "/events", "gzip")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.True(t, w.Flushed)
+	assert.Contains(t, w.Body.String(), "event: tick\n")
+
+	// the Content-Type is sniffed like net/http does.
+	w = perform(s, "/sniffed", "gzip")
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, Gzip, w.Header().Get("Content-Encoding"))
+
+	s = gweb.NewServer()
+	s.Global(New(Config{MinSize: -1, ContentTypes: []string{"image/"}, ExcludedContentTypes: []string{"image/gif"}}))
+	s.GET("/small", smallHandler)
+	s.GET("/png", pngHandler)
+	w = perform(s, "/png", "gzip")
+	assert.Equal(t, Gzip, w.Header().Get("Content-Encoding"))
+	w = perform(s, "/small", "gzip")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+}
+
+func TestPanicKeepsErrorResponse(t *testing.T) {
+	s := gweb.NewServer()
+	s.Global(recovery.New(recovery.Config{Output: io.Discard}), New(Config{}))
+	s.GET("/panic", func(c *gweb.Context) {
+		c.String(http.StatusOK, "partial")
+		panic("boom")
+	})
+	w := perform(s, "/panic", "gzip")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, w.Body.String(), "partial")
+}
+
+func TestWebSocketUpgradeSkipped(t *testing.T) {
+	s := gweb.NewServer()
+	s.Global(New(Config{MinSize: -1}))
+	s.WebSocket("/ws", func(c *gweb.Context) {
+		conn := c.WebSocket()
+		_, p, err := conn.ReadMessage()
+		if err == nil {
+			conn.WriteMessage(websocket.TextMessage, p)
+		}
+	})
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	conn, resp, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws",
+		http.Header{"Accept-Encoding": {"gzip"}})
+	assert.NoError(t, err)
+	defer conn.Close()
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+	assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("hi")))
+	_, p, err := conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, "hi", string(p))
+}
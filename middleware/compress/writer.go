@@ -0,0 +1,177 @@
+package compress
+
+import (
+	"bufio"
+	"github.com/chen-zyc/gweb"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// compressWriter buffers the beginning of the body until it decides
+// whether the response is compressed, then writes through the encoder or
+// directly to the wrapped writer.
+type compressWriter struct {
+	gweb.ResponseWriter
+	status   int
+	minSize  int
+	encoding string
+	pools    map[string]*sync.Pool
+	eligible func(contentType string) bool
+
+	buf     []byte
+	size    int  // bytes written by the handlers
+	written bool // the handlers sent the header
+	decided bool
+	enc     encoder
+}
+
+var _ gweb.ResponseWriter = (*compressWriter)(nil)
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *compressWriter) WriteHeaderNow() {
+	w.written = true
+	if w.decided {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	w.written = true
+	w.size += len(data)
+	if !w.decided {
+		w.buf = append(w.buf, data...)
+		if len(w.buf) < w.minSize {
+			return len(data), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) Status() int {
+	if w.decided {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+// Size returns the number of bytes written by the handlers, before
+// compression.
+func (w *compressWriter) Size() int { return w.size }
+
+func (w *compressWriter) Written() bool {
+	return w.written || w.ResponseWriter.Written()
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(true)
+	}
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	return w.ResponseWriter.Hijack()
+}
+
+// decide chooses whether to compress the response and writes the header
+// and the buffered body. large reports whether the body reached the
+// minimum size, or is streamed.
+func (w *compressWriter) decide(large bool) error {
+	w.decided = true
+	h := w.Header()
+	contentType := h.Get("Content-Type")
+	if contentType == "" && len(w.buf) > 0 {
+		// what net/http would send.
+		contentType = http.DetectContentType(w.buf)
+		h.Set("Content-Type", contentType)
+	}
+
+	compress := bodyAllowed(w.status) && h.Get("Content-Encoding") == "" &&
+		!strings.HasPrefix(contentType, "text/event-stream") && w.eligible(contentType)
+	if compress {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	if compress && large && w.encoding != "" {
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+		w.enc = w.pools[w.encoding].Get().(encoder)
+		w.enc.Reset(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) == 0 {
+		if w.written {
+			w.ResponseWriter.WriteHeaderNow()
+		}
+		return nil
+	}
+	buf := w.buf
+	w.buf = nil
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// close sends the rest of the response once the handlers returned.
+func (w *compressWriter) close() {
+	if !w.decided {
+		w.decide(len(w.buf) >= w.minSize)
+	}
+	if w.enc != nil {
+		w.enc.Close()
+	}
+	w.release()
+}
+
+// release puts the encoder back in its pool.
+func (w *compressWriter) release() {
+	if w.enc != nil {
+		w.enc.Reset(nil)
+		w.pools[w.encoding].Put(w.enc)
+		w.enc = nil
+	}
+}
+
+// bodyAllowed reports whether a response with the status can have a body.
+func bodyAllowed(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent, status == http.StatusNotModified:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,170 @@
+// Package etag provides a middleware adding entity tags to the responses
+// and answering conditional GET and HEAD requests with 304 Not Modified.
+package etag
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/chen-zyc/gweb"
+	"net"
+	"net/http"
+)
+
+type Config struct {
+	// Weak makes the computed entity tags weak.
+	Weak bool
+}
+
+// Default returns the middleware computing strong entity tags.
+func Default() gweb.Handler {
+	return New(Config{})
+}
+
+// New returns the middleware buffering the 200 responses to GET and HEAD
+// requests. Unless the handlers set an ETag header, the entity tag is the
+// hash of the body. The request is then evaluated with
+// Context.CheckPreconditions, also using a Last-Modified header set by the
+// handlers, and the body is only sent if the preconditions pass.
+//
+// Other methods are not buffered: their handlers should call
+// Context.CheckPreconditions with the current version of the resource
+// before changing it. Responses which are flushed or hijacked are sent as
+// is, without entity tag.
+func New(cfg Config) gweb.Handler {
+	return func(c *gweb.Context) {
+		req := c.Request()
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		w := c.Writer()
+		bw := &bufferedWriter{ResponseWriter: w, status: http.StatusOK}
+		c.SetWriter(bw)
+		defer c.SetWriter(w)
+		c.Next()
+		c.SetWriter(w)
+
+		if bw.passthrough {
+			return
+		}
+		if bw.status != http.StatusOK {
+			bw.flushTo(w)
+			return
+		}
+
+		h := w.Header()
+		etag := h.Get("ETag")
+		// a handler may skip the body of HEAD requests, an empty body does
+		// not identify the representation then.
+		if etag == "" && (bw.buf.Len() > 0 || req.Method == http.MethodGet) {
+			if cfg.Weak {
+				etag = gweb.WeakETag(bw.buf.Bytes())
+			} else {
+				etag = gweb.StrongETag(bw.buf.Bytes())
+			}
+		}
+		lastModified, _ := http.ParseTime(h.Get("Last-Modified"))
+		if c.CheckPreconditions(etag, lastModified) {
+			return
+		}
+		bw.flushTo(w)
+	}
+}
+
+// bufferedWriter keeps the body in memory until the handlers return. It
+// switches to writing through when the handlers flush or hijack.
+type bufferedWriter struct {
+	gweb.ResponseWriter
+	buf         bytes.Buffer
+	status      int
+	written     bool
+	passthrough bool
+}
+
+var _ gweb.ResponseWriter = (*bufferedWriter)(nil)
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	w.written = true
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+	w.written = true
+	return w.buf.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.WriteString(s)
+	}
+	w.written = true
+	return w.buf.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	if w.passthrough {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	if w.passthrough {
+		return w.ResponseWriter.Size()
+	}
+	return w.buf.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	if w.passthrough {
+		return w.ResponseWriter.Written()
+	}
+	return w.written
+}
+
+func (w *bufferedWriter) Flush() {
+	w.writeThrough()
+	w.ResponseWriter.Flush()
+}
+
+func (w *bufferedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := w.ResponseWriter.Hijack()
+	if err == nil {
+		w.passthrough = true
+	}
+	return conn, brw, err
+}
+
+// writeThrough sends what was buffered and stops buffering.
+func (w *bufferedWriter) writeThrough() {
+	if !w.passthrough {
+		w.passthrough = true
+		w.flushTo(w.ResponseWriter)
+	}
+}
+
+func (w *bufferedWriter) flushTo(dst gweb.ResponseWriter) {
+	dst.WriteHeader(w.status)
+	if w.buf.Len() > 0 {
+		dst.Write(w.buf.Bytes())
+	} else if w.written {
+		dst.WriteHeaderNow()
+	}
+}
@@ -0,0 +1,144 @@
+package etag
+
+import (
+	"github.com/chen-zyc/gweb"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func perform(s *gweb.Server, method, path string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	return w
+}
+
+func usersHandler(c *gweb.Context) {
+	c.JSON(http.StatusOK, gweb.H{"name": "gopher"})
+}
+
+func TestComputedETag(t *testing.T) {
+	s := gweb.NewServer()
+	s.Global(New(Config{}))
+	s.GET("/users", usersHandler)
+
+	w := perform(s, http.MethodGet, "/users")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "{\"name\":\"gopher\"}\n", w.Body.String())
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, gweb.StrongETag([]byte("{\"name\":\"gopher\"}\n")), etag)
+
+	w = perform(s, http.MethodGet, "/users", "If-None-Match", `"other", `+etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+	assert.Empty(t, w.Header().Get("Content-Type"))
+
+	// the weak comparison of If-None-Match.
+	w = perform(s, http.MethodGet, "/users", "If-None-Match", "W/"+etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = perform(s, http.MethodGet, "/users", "If-Match", `"other"`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Empty(t, w.Body.String())
+	w = perform(s, http.MethodGet, "/users", "If-Match", etag)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	s = gweb.NewServer()
+	s.Global(New(Config{Weak: true}))
+	s.GET("/users", usersHandler)
+	w = perform(s, http.MethodGet, "/users")
+	assert.Equal(t, "W/"+etag, w.Header().Get("ETag"))
+	// a weak tag never satisfies If-Match.
+	w = perform(s, http.MethodGet, "/users", "If-Match", "W/"+etag)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+}
+
+func TestSuppliedValidators(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := gweb.NewServer()
+	s.Global(New(Config{}))
+	s.GET("/versioned", func(c *gweb.Context) {
+		c.Header("ETag", gweb.VersionETag("v7", false))
+		c.Header("Last-Modified", modified.Format(http.TimeFormat))
+		c.String(http.StatusOK, "body")
+	})
+
+	w := perform(s, http.MethodGet, "/versioned", "If-None-Match", `"v7"`)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = perform(s, http.MethodGet, "/versioned", "If-None-Match", `"v6"`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "body", w.Body.String())
+
+	w = perform(s, http.MethodGet, "/versioned", "If-Modified-Since", modified.Format(http.TimeFormat))
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	w = perform(s, http.MethodGet, "/versioned", "If-Modified-Since", modified.Add(-time.Hour).Format(http.TimeFormat))
+	assert.Equal(t, http.StatusOK, w.Code)
+	// If-None-Match takes precedence over If-Modified-Since.
+	w = perform(s, http.MethodGet, "/versioned",
+		"If-None-Match", `"v6"`, "If-Modified-Since", modified.Format(http.TimeFormat))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = perform(s, http.MethodGet, "/versioned", "If-Unmodified-Since", modified.Add(-time.Hour).Format(http.TimeFormat))
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+}
+
+func TestSkippedResponses(t *testing.T) {
+	s := gweb.NewServer()
+	s.Global(New(Config{}))
+	s.HEAD("/users", func(c *gweb.Context) {
+		c.Status(http.StatusOK)
+	})
+	s.POST("/users", func(c *gweb.Context) {
+		c.String(http.StatusCreated, "created")
+	})
+	s.GET("/missing", func(c *gweb.Context) {
+		c.String(http.StatusNotFound, "missing")
+	})
+	s.GET("/stream", func(c *gweb.Context) {
+		c.Stream(func(w io.Writer) bool {
+			io.WriteString(w, "chunk")
+			return false
+		})
+	})
+
+	w := perform(s, http.MethodHead, "/users")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("ETag"))
+
+	w = perform(s, http.MethodGet, "/missing", "If-None-Match", "*")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "missing", w.Body.String())
+	assert.Empty(t, w.Header().Get("ETag"))
+
+	w = perform(s, http.MethodGet, "/stream")
+	assert.Equal(t, "chunk", w.Body.String())
+	assert.True(t, w.Flushed)
+	assert.Empty(t, w.Header().Get("ETag"))
+
+	w = perform(s, http.MethodPost, "/users", "If-Match", `"x"`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestFailedHijack(t *testing.T) {
+	s := gweb.NewServer()
+	s.Global(New(Config{}))
+	s.GET("/hijack", func(c *gweb.Context) {
+		// the recorder can not be hijacked, the response is still buffered.
+		_, _, err := c.Writer().Hijack()
+		assert.Error(t, err)
+		c.String(http.StatusOK, "body")
+	})
+
+	w := perform(s, http.MethodGet, "/hijack")
+	assert.Equal(t, "body", w.Body.String())
+	assert.Equal(t, gweb.StrongETag([]byte("body")), w.Header().Get("ETag"))
+}
@@ -0,0 +1,244 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Text writes a human readable line:
+//
+//	[gweb] 2006/01/02 - 15:04:05 | 200 |    1.234ms | 127.0.0.1 | GET /users/42 (/users/:id) | 12 bytes | "curl/7.64.1"
+func Text(w io.Writer, e *Entry) error {
+	route := ""
+	if e.Route != "" && e.Route != e.Path {
+		route = " (" + e.Route + ")"
+	}
+	_, err := fmt.Fprintf(w, "[gweb] %s | %3d | %12v | %s | %s %s%s | %d bytes | %q\n",
+		e.Time.Format("2006/01/02 - 15:04:05"),
+		e.Status,
+		e.Latency,
+		e.ClientIP,
+		escape(e.Method),
+		escape(e.Path),
+		route,
+		e.Size,
+		e.UserAgent,
+	)
+	return err
+}
+
+type jsonEntry struct {
+	Time      string  `json:"time"`
+	Method    string  `json:"method"`
+	Path      string  `json:"path"`
+	Query     string  `json:"query,omitempty"`
+	Route     string  `json:"route,omitempty"`
+	Status    int     `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Size      int     `json:"bytes"`
+	ClientIP  string  `json:"client_ip"`
+	UserAgent string  `json:"user_agent"`
+	Referer   string  `json:"referer,omitempty"`
+}
+
+// JSON writes an entry as a JSON object on a single line.
+func JSON(w io.Writer, e *Entry) error {
+	return json.NewEncoder(w).Encode(jsonEntry{
+		Time:      e.Time.Format(time.RFC3339Nano),
+		Method:    e.Method,
+		Path:      e.Path,
+		Query:     e.Query,
+		Route:     e.Route,
+		Status:    e.Status,
+		LatencyMS: float64(e.Latency) / float64(time.Millisecond),
+		Size:      e.Size,
+		ClientIP:  e.ClientIP,
+		UserAgent: e.UserAgent,
+		Referer:   e.Referer,
+	})
+}
+
+// ApacheCombinedLayout is the layout of the Apache combined log format.
+const ApacheCombinedLayout = `%h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-Agent}i"`
+
+// ApacheCombined writes an entry in the Apache combined log format.
+var ApacheCombined = Apache(ApacheCombinedLayout)
+
+// Apache returns a Format writing entries with an Apache LogFormat layout.
+// The supported directives are:
+//
+//	%h       client IP
+//	%l       remote logname, always "-"
+//	%u       user of the basic authentication or "-"
+//	%t       time the request was received, e.g. [10/Oct/2000:13:55:36 -0700]
+//	%r       first line of the request
+//	%s, %>s  status
+//	%b       size of the body, "-" for no bytes
+//	%B       size of the body
+//	%D       latency in microseconds
+//	%T       latency in seconds
+//	%m       method
+//	%U       path
+//	%q       query string, prefixed with '?' if not empty
+//	%H       protocol
+//	%v       host
+//	%R       registered route path (gweb extension)
+//	%{X}i    request header X
+//	%{X}o    response header X
+//	%%       literal '%'
+//
+// The values taken from the request are escaped as Apache does, see escape.
+// Unknown directives are written as is. A newline is appended to each entry.
+func Apache(layout string) Format {
+	return func(w io.Writer, e *Entry) error {
+		var b strings.Builder
+		for i := 0; i < len(layout); i++ {
+			c := layout[i]
+			if c != '%' || i == len(layout)-1 {
+				b.WriteByte(c)
+				continue
+			}
+
+			i++
+			if layout[i] == '>' && i+1 < len(layout) { // %>s
+				i++
+			}
+			var arg string
+			if layout[i] == '{' {
+				end := strings.IndexByte(layout[i:], '}')
+				if end < 0 || i+end+1 >= len(layout) {
+					b.WriteString(layout[i-1:])
+					break
+				}
+				arg = layout[i+1 : i+end]
+				i += end + 1
+			}
+			writeDirective(&b, layout[i], arg, e)
+		}
+		b.WriteByte('\n')
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+}
+
+func writeDirective(b *strings.Builder, d byte, arg string, e *Entry) {
+	switch d {
+	case 'h':
+		b.WriteString(dash(e.ClientIP))
+	case 'l':
+		b.WriteByte('-')
+	case 'u':
+		user := ""
+		if e.Request != nil {
+			user, _, _ = e.Request.BasicAuth()
+		}
+		b.WriteString(dash(escape(user)))
+	case 't':
+		b.WriteString(e.Time.Format("[02/Jan/2006:15:04:05 -0700]"))
+	case 'r':
+		b.WriteString(escape(e.Method))
+		b.WriteByte(' ')
+		b.WriteString(escape(e.Path))
+		if e.Query != "" {
+			b.WriteByte('?')
+			b.WriteString(escape(e.Query))
+		}
+		b.WriteByte(' ')
+		b.WriteString(escape(e.Proto))
+	case 's':
+		b.WriteString(strconv.Itoa(e.Status))
+	case 'b':
+		if e.Size == 0 {
+			b.WriteByte('-')
+		} else {
+			b.WriteString(strconv.Itoa(e.Size))
+		}
+	case 'B':
+		b.WriteString(strconv.Itoa(e.Size))
+	case 'D':
+		b.WriteString(strconv.FormatInt(int64(e.Latency/time.Microsecond), 10))
+	case 'T':
+		b.WriteString(strconv.FormatInt(int64(e.Latency/time.Second), 10))
+	case 'm':
+		b.WriteString(escape(e.Method))
+	case 'U':
+		b.WriteString(escape(e.Path))
+	case 'q':
+		if e.Query != "" {
+			b.WriteByte('?')
+			b.WriteString(escape(e.Query))
+		}
+	case 'H':
+		b.WriteString(escape(e.Proto))
+	case 'v':
+		b.WriteString(dash(escape(e.Host)))
+	case 'R':
+		b.WriteString(dash(e.Route))
+	case 'i':
+		val := ""
+		if e.Request != nil {
+			val = e.Request.Header.Get(arg)
+		}
+		b.WriteString(dash(escape(val)))
+	case 'o':
+		val := ""
+		if e.Header != nil {
+			val = e.Header.Get(arg)
+		}
+		b.WriteString(dash(escape(val)))
+	case '%':
+		b.WriteByte('%')
+	default:
+		b.WriteByte('%')
+		if arg != "" {
+			b.WriteString("{" + arg + "}")
+		}
+		b.WriteByte(d)
+	}
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// escape escapes the control characters, '"' and '\' of s as Apache does,
+// e.g. \n, \" and \x1b, so that a request can neither forge a log line nor
+// break the quoting of a field.
+func escape(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= 0x20 && s[i] != 0x7f && s[i] != '"' && s[i] != '\\' {
+		i++
+	}
+	if i == len(s) {
+		return s
+	}
+	var b strings.Builder
+	b.WriteString(s[:i])
+	for ; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if c < 0x20 || c == 0x7f {
+				fmt.Fprintf(&b, `\x%02x`, c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	return b.String()
+}
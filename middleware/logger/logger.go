@@ -0,0 +1,144 @@
+// Package logger provides an access-logging middleware for gweb.
+package logger
+
+import (
+	"bytes"
+	"github.com/chen-zyc/gweb"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Entry is a single access log record.
+type Entry struct {
+	Time      time.Time
+	Method    string
+	Path      string
+	Query     string
+	Proto     string
+	Host      string
+	Route     string // registered path of the route, e.g. "/users/:id"
+	Status    int
+	Latency   time.Duration
+	Size      int
+	ClientIP  string
+	UserAgent string
+	Referer   string
+
+	// Request is the logged request, Header the header of the response.
+	// They are only valid during the call of the Format function.
+	Request *http.Request
+	Header  http.Header
+}
+
+// Format writes an entry to w. The output of a single call is written to
+// Config.Output with one write.
+type Format func(w io.Writer, e *Entry) error
+
+type Config struct {
+	// Output is the destination of the log, os.Stdout by default.
+	Output io.Writer
+	// Format is the format of each line, Text by default.
+	Format Format
+	// SkipPaths are request paths which are not logged, e.g. "/health".
+	SkipPaths []string
+	// SampleRate is the fraction of requests logged, between 0 and 1.
+	// Zero logs every request. Server errors (5xx) are always logged.
+	SampleRate float64
+	// Sample decides whether an entry is logged, it replaces SampleRate.
+	Sample func(e *Entry) bool
+}
+
+// Default returns the middleware logging every request as Text to
+// os.Stdout.
+func Default() gweb.Handler {
+	return New(Config{})
+}
+
+// New returns the middleware logging the requests after the handlers ran.
+func New(cfg Config) gweb.Handler {
+	out := cfg.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	format := cfg.Format
+	if format == nil {
+		format = Text
+	}
+	skip := make(map[string]bool, len(cfg.SkipPaths))
+	for _, p := range cfg.SkipPaths {
+		skip[p] = true
+	}
+	sample := cfg.Sample
+	if sample == nil && cfg.SampleRate > 0 && cfg.SampleRate < 1 {
+		sample = rateSampler(cfg.SampleRate)
+	}
+
+	var (
+		mu      sync.Mutex
+		bufPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+	)
+
+	return func(c *gweb.Context) {
+		start := time.Now()
+		req := c.Request()
+		path, query := req.URL.Path, req.URL.RawQuery
+
+		c.Next()
+
+		if skip[path] {
+			return
+		}
+
+		w := c.Writer()
+		e := &Entry{
+			Time:      start,
+			Method:    req.Method,
+			Path:      path,
+			Query:     query,
+			Proto:     req.Proto,
+			Host:      req.Host,
+			Route:     c.FullPath(),
+			Status:    w.Status(),
+			Latency:   time.Since(start),
+			Size:      w.Size(),
+			ClientIP:  c.ClientIP(),
+			UserAgent: req.UserAgent(),
+			Referer:   req.Referer(),
+			Request:   req,
+			Header:    w.Header(),
+		}
+		if sample != nil && !sample(e) {
+			return
+		}
+
+		buf := bufPool.Get().(*bytes.Buffer)
+		buf.Reset()
+		defer bufPool.Put(buf)
+		if err := format(buf, e); err != nil {
+			return
+		}
+
+		mu.Lock()
+		out.Write(buf.Bytes())
+		mu.Unlock()
+	}
+}
+
+func rateSampler(rate float64) func(e *Entry) bool {
+	var (
+		mu  sync.Mutex
+		rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+	)
+	return func(e *Entry) bool {
+		if e.Status >= http.StatusInternalServerError {
+			return true
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return rnd.Float64() < rate
+	}
+}
@@ -0,0 +1,141 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/chen-zyc/gweb"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newServer(cfg Config) *gweb.Server {
+	s := gweb.NewServer()
+	s.Global(New(cfg))
+	s.GET("/users/:id", func(c *gweb.Context) {
+		c.Header("X-Request-Id", "abc")
+		c.String(http.StatusOK, "user %s", c.Param("id"))
+	})
+	s.GET("/health", func(c *gweb.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	s.GET("/fail", func(c *gweb.Context) {
+		c.Status(http.StatusInternalServerError)
+	})
+	return s
+}
+
+func request(s *gweb.Server, path string) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("Referer", "http://example.com/")
+	s.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestTextFormat(t *testing.T) {
+	var buf bytes.Buffer
+	s := newServer(Config{Output: &buf})
+	request(s, "/users/42")
+
+	line := buf.String()
+	assert.True(t, strings.HasPrefix(line, "[gweb] "))
+	assert.Contains(t, line, "| 200 |")
+	assert.Contains(t, line, "| 10.0.0.1 |")
+	assert.Contains(t, line, "| GET /users/42 (/users/:id) |")
+	assert.Contains(t, line, "| 7 bytes |")
+	assert.True(t, strings.HasSuffix(line, "| \"test-agent\"\n"))
+}
+
+func TestJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	s := newServer(Config{Output: &buf, Format: JSON})
+	request(s, "/users/42?verbose=1")
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "GET", entry["method"])
+	assert.Equal(t, "/users/42", entry["path"])
+	assert.Equal(t, "verbose=1", entry["query"])
+	assert.Equal(t, "/users/:id", entry["route"])
+	assert.Equal(t, float64(200), entry["status"])
+	assert.Equal(t, float64(7), entry["bytes"])
+	assert.Equal(t, "10.0.0.1", entry["client_ip"])
+	assert.Equal(t, "test-agent", entry["user_agent"])
+	assert.Contains(t, entry, "latency_ms")
+}
+
+func TestApacheFormat(t *testing.T) {
+	e := &Entry{
+		Time:      time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600)),
+		Method:    "GET",
+		Path:      "/apache_pb.gif",
+		Query:     "a=1",
+		Proto:     "HTTP/1.0",
+		Route:     "/apache_pb.gif",
+		Status:    200,
+		Size:      2326,
+		Latency:   1500 * time.Microsecond,
+		ClientIP:  "127.0.0.1",
+		UserAgent: "Mozilla/4.08",
+		Request:   httptest.NewRequest("GET", "/apache_pb.gif", nil),
+		Header:    http.Header{"X-Request-Id": {"abc"}},
+	}
+	e.Request.SetBasicAuth("frank", "secret")
+	e.Request.Header.Set("Referer", "http://www.example.com/start.html")
+	e.Request.Header.Set("User-Agent", "Mozilla/4.08")
+
+	var buf bytes.Buffer
+	assert.NoError(t, ApacheCombined(&buf, e))
+	assert.Equal(t, `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif?a=1 HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08"`+"\n", buf.String())
+
+	buf.Reset()
+	e.Size = 0
+	assert.NoError(t, Apache(`%m %U%q %s %b %B %D %{X-Request-Id}o %{Missing}i %% %z %R`)(&buf, e))
+	assert.Equal(t, "GET /apache_pb.gif?a=1 200 - 0 1500 abc - % %z /apache_pb.gif\n", buf.String())
+}
+
+func TestLogInjection(t *testing.T) {
+	var buf bytes.Buffer
+	s := newServer(Config{Output: &buf})
+	request(s, "/users/x%0A[gweb]%20200%20forged")
+	assert.Equal(t, 1, strings.Count(buf.String(), "\n"))
+	assert.Contains(t, buf.String(), `| GET /users/x\n[gweb] 200 forged (/users/:id) |`)
+
+	e := &Entry{
+		Method:  "GET",
+		Path:    "/x\n1.2.3.4 - - \x1b",
+		Proto:   "HTTP/1.1",
+		Request: httptest.NewRequest("GET", "/", nil),
+	}
+	e.Request.Header.Set("User-Agent", `evil" "injected`)
+	buf.Reset()
+	assert.NoError(t, Apache(`"%r" "%{User-Agent}i"`)(&buf, e))
+	assert.Equal(t, `"GET /x\n1.2.3.4 - - \x1b HTTP/1.1" "evil\" \"injected"`+"\n", buf.String())
+}
+
+func TestSkipPathsAndSampling(t *testing.T) {
+	var buf bytes.Buffer
+	s := newServer(Config{Output: &buf, SkipPaths: []string{"/health"}})
+	request(s, "/health")
+	assert.Empty(t, buf.String())
+
+	buf.Reset()
+	var sampled []int
+	s = newServer(Config{Output: &buf, Sample: func(e *Entry) bool {
+		sampled = append(sampled, e.Status)
+		return e.Status >= 500
+	}})
+	request(s, "/users/1")
+	request(s, "/fail")
+	assert.Equal(t, []int{200, 500}, sampled)
+	assert.Equal(t, 1, strings.Count(buf.String(), "\n"))
+	assert.Contains(t, buf.String(), "| 500 |")
+
+	// server errors are always logged by the rate sampler.
+	sample := rateSampler(0.0000001)
+	assert.True(t, sample(&Entry{Status: 503}))
+}
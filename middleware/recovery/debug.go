@@ -0,0 +1,33 @@
+package recovery
+
+import "html/template"
+
+var debugTemplate = template.Must(template.New("debug").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>panic: {{ .Panic }}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h1 { color: #b00; font-size: 1.4em; }
+.frame { margin-bottom: 1.5em; }
+.func { font-weight: bold; }
+.file { color: #666; }
+pre { background: #f6f6f6; padding: .5em; overflow-x: auto; }
+.current { background: #fdd; display: block; }
+</style>
+</head>
+<body>
+<h1>panic: {{ .Panic }}</h1>
+<p>{{ .Method }} {{ .Path }}{{ if .Route }} (route {{ .Route }}){{ end }} at {{ .Time.Format "2006-01-02 15:04:05" }}</p>
+{{ range .Stack }}
+<div class="frame">
+<div class="func">{{ .Function }}</div>
+<div class="file">{{ .File }}:{{ .Line }}</div>
+{{ if .Source }}<pre>{{ range .Source }}<span{{ if .Current }} class="current"{{ end }}>{{ printf "%5d" .Number }}  {{ .Code }}</span>
+{{ end }}</pre>{{ end }}
+</div>
+{{ end }}
+</body>
+</html>
+`))
@@ -0,0 +1,132 @@
+// Package recovery provides a middleware recovering from panics in the
+// handlers of a gweb server.
+package recovery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/chen-zyc/gweb"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Event describes a recovered panic.
+type Event struct {
+	Time   time.Time `json:"time"`
+	Panic  string    `json:"panic"`
+	Method string    `json:"method"`
+	Path   string    `json:"path"`
+	Route  string    `json:"route,omitempty"`
+	Stack  []Frame   `json:"stack"`
+	// Err is the value passed to panic.
+	Err interface{} `json:"-"`
+}
+
+type Config struct {
+	// Output receives the events as JSON lines, os.Stderr by default.
+	Output io.Writer
+	// OnPanic is called with the event instead of writing it to Output.
+	OnPanic func(c *gweb.Context, e *Event)
+	// Debug renders an HTML page with the stack and the source code instead
+	// of the plain 500 response. Do not enable it in production.
+	Debug bool
+	// ContextLines is the number of source lines shown around each line of
+	// the stack, 3 by default. A negative value disables the snippets.
+	ContextLines int
+}
+
+// Default returns the middleware writing the events to os.Stderr.
+func Default() gweb.Handler {
+	return New(Config{})
+}
+
+// New returns the middleware recovering from the panics of the handlers
+// called after it. It emits an event, responds with 500 Internal Server
+// Error unless the header was already sent and aborts the chain.
+//
+// The panics with http.ErrAbortHandler are propagated to net/http, which
+// aborts the response silently.
+func New(cfg Config) gweb.Handler {
+	out := cfg.Output
+	if out == nil {
+		out = os.Stderr
+	}
+	contextLines := cfg.ContextLines
+	if contextLines == 0 {
+		contextLines = 3
+	}
+	var mu sync.Mutex
+
+	return func(c *gweb.Context) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+
+			req := c.Request()
+			e := &Event{
+				Time:   time.Now(),
+				Panic:  fmt.Sprint(err),
+				Method: req.Method,
+				Path:   req.URL.Path,
+				Route:  c.FullPath(),
+				Stack:  stack(3, contextLines),
+				Err:    err,
+			}
+
+			if cfg.OnPanic != nil {
+				cfg.OnPanic(c, e)
+			} else if b, jsonErr := json.Marshal(e); jsonErr == nil {
+				mu.Lock()
+				out.Write(append(b, '\n'))
+				mu.Unlock()
+			}
+
+			// the client is gone, nothing can be written.
+			if isBrokenPipe(err) {
+				c.Abort()
+				return
+			}
+			if c.Writer().Written() {
+				c.Abort()
+				return
+			}
+			if cfg.Debug {
+				c.Header("Content-Type", "text/html; charset=utf-8")
+				c.AbortWithStatus(http.StatusInternalServerError)
+				debugTemplate.Execute(c.Writer(), e)
+				return
+			}
+			c.Abort()
+			c.String(http.StatusInternalServerError, "%s", http.StatusText(http.StatusInternalServerError))
+		}()
+		c.Next()
+	}
+}
+
+func isBrokenPipe(err interface{}) bool {
+	e, ok := err.(error)
+	if !ok {
+		return false
+	}
+	var opErr *net.OpError
+	if !errors.As(e, &opErr) {
+		return false
+	}
+	if errors.Is(opErr, syscall.EPIPE) || errors.Is(opErr, syscall.ECONNRESET) {
+		return true
+	}
+	msg := strings.ToLower(opErr.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
@@ -0,0 +1,116 @@
+package recovery
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/chen-zyc/gweb"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func panicHandler(c *gweb.Context) {
+	panic("boom")
+}
+
+func perform(s *gweb.Server, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestRecoveryEvent(t *testing.T) {
+	var buf bytes.Buffer
+	s := gweb.NewServer()
+	s.Global(New(Config{Output: &buf}))
+	s.GET("/users/:id", panicHandler, func(c *gweb.Context) {
+		t.Error("the chain must be aborted")
+	})
+
+	w := perform(s, "/users/1")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "Internal Server Error", w.Body.String())
+
+	var e Event
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &e))
+	assert.Equal(t, "boom", e.Panic)
+	assert.Equal(t, "GET", e.Method)
+	assert.Equal(t, "/users/1", e.Path)
+	assert.Equal(t, "/users/:id", e.Route)
+	assert.NotEmpty(t, e.Stack)
+
+	top := e.Stack[0]
+	assert.Equal(t, "github.com/chen-zyc/gweb/middleware/recovery.panicHandler", top.Function)
+	assert.True(t, strings.HasSuffix(top.File, "recovery_test.go"))
+	assert.Len(t, top.Source, 7)
+	for _, line := range top.Source {
+		assert.Equal(t, line.Number == top.Line, line.Current)
+		if line.Current {
+			assert.Equal(t, "\tpanic(\"boom\")", line.Code)
+		}
+	}
+}
+
+func TestRecoveryRuntimeError(t *testing.T) {
+	var event *Event
+	s := gweb.NewServer()
+	s.Global(New(Config{OnPanic: func(c *gweb.Context, e *Event) { event = e }, ContextLines: -1}))
+	s.GET("/nil", func(c *gweb.Context) {
+		var m map[string]int
+		m["a"] = 1
+	})
+
+	w := perform(s, "/nil")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotNil(t, event)
+	assert.Contains(t, event.Panic, "nil map")
+	assert.False(t, strings.HasPrefix(event.Stack[0].Function, "runtime."))
+	assert.Empty(t, event.Stack[0].Source)
+}
+
+func TestRecoveryHeaderAlreadySent(t *testing.T) {
+	s := gweb.NewServer()
+	s.Global(New(Config{OnPanic: func(*gweb.Context, *Event) {}}))
+	s.GET("/partial", func(c *gweb.Context) {
+		c.String(http.StatusOK, "partial")
+		panic("boom")
+	})
+
+	w := perform(s, "/partial")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "partial", w.Body.String())
+}
+
+func TestRecoveryDebugPage(t *testing.T) {
+	s := gweb.NewServer()
+	s.Global(New(Config{Debug: true, OnPanic: func(*gweb.Context, *Event) {}}))
+	s.GET("/debug", panicHandler)
+
+	w := perform(s, "/debug")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "<h1>panic: boom</h1>")
+	assert.Contains(t, w.Body.String(), "recovery.panicHandler")
+	assert.Contains(t, w.Body.String(), `<span class="current">`)
+}
+
+func TestRecoveryAbortHandler(t *testing.T) {
+	s := gweb.NewServer()
+	s.Global(New(Config{OnPanic: func(*gweb.Context, *Event) { t.Error("unexpected event") }}))
+	s.GET("/abort", func(c *gweb.Context) {
+		panic(http.ErrAbortHandler)
+	})
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() { perform(s, "/abort") })
+}
+
+func TestIsBrokenPipe(t *testing.T) {
+	assert.True(t, isBrokenPipe(&net.OpError{Op: "write", Err: syscall.EPIPE}))
+	assert.True(t, isBrokenPipe(&net.OpError{Op: "write", Err: syscall.ECONNRESET}))
+	assert.False(t, isBrokenPipe(errors.New("broken pipe")))
+	assert.False(t, isBrokenPipe("boom"))
+}
@@ -0,0 +1,91 @@
+package recovery
+
+import (
+	"bytes"
+	"io/ioutil"
+	"runtime"
+	"strings"
+)
+
+// Frame is a function call of the stack of a panic.
+type Frame struct {
+	Function string       `json:"function"`
+	File     string       `json:"file"`
+	Line     int          `json:"line"`
+	Source   []SourceLine `json:"source,omitempty"`
+}
+
+// SourceLine is a line of source code around a Frame.
+type SourceLine struct {
+	Number  int    `json:"number"`
+	Code    string `json:"code"`
+	Current bool   `json:"current,omitempty"`
+}
+
+// stack returns the stack of the panicking goroutine, starting at the
+// function which panicked. skip is passed to runtime.Callers, the frames
+// before runtime.gopanic are dropped anyway.
+func stack(skip, contextLines int) []Frame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack []Frame
+	files := make(map[string][][]byte)
+	inRuntime := false
+	for {
+		f, more := frames.Next()
+		if f.Function == "runtime.gopanic" {
+			// drop the recovery frames and the runtime frames raising the
+			// panic, e.g. runtime.sigpanic, the next one panicked.
+			stack = stack[:0]
+			inRuntime = true
+		} else if inRuntime && strings.HasPrefix(f.Function, "runtime.") {
+			// skip
+		} else {
+			inRuntime = false
+			frame := Frame{Function: f.Function, File: f.File, Line: f.Line}
+			if contextLines > 0 {
+				frame.Source = source(files, f.File, f.Line, contextLines)
+			}
+			stack = append(stack, frame)
+		}
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+// source returns the lines of file around line. The files are cached in
+// files for the duration of a single stack.
+func source(files map[string][][]byte, file string, line, contextLines int) []SourceLine {
+	lines, ok := files[file]
+	if !ok {
+		data, err := ioutil.ReadFile(file)
+		if err == nil {
+			lines = bytes.Split(data, []byte{'\n'})
+		}
+		files[file] = lines
+	}
+	if line <= 0 || line > len(lines) {
+		return nil
+	}
+
+	start, end := line-contextLines, line+contextLines
+	if start < 1 {
+		start = 1
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+	src := make([]SourceLine, 0, end-start+1)
+	for i := start; i <= end; i++ {
+		src = append(src, SourceLine{
+			Number:  i,
+			Code:    strings.TrimRight(string(lines[i-1]), "\r"),
+			Current: i == line,
+		})
+	}
+	return src
+}
@@ -0,0 +1,97 @@
+package gweb
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// MultipartForm parses the multipart body, keeping at most
+// Server.MaxMultipartMemory bytes in memory, and returns the form.
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	if err := c.req.ParseMultipartForm(c.s.MaxMultipartMemory); err != nil {
+		return nil, err
+	}
+	return c.req.MultipartForm, nil
+}
+
+// FormFile returns the first file uploaded with the form key name.
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+	if files := form.File[name]; len(files) > 0 {
+		return files[0], nil
+	}
+	return nil, http.ErrMissingFile
+}
+
+// SaveUploadedFile copies the uploaded file to dst, creating the parent
+// directories if needed.
+func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err = os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, src); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// MultipartReader returns a reader of the parts of a multipart body, which
+// are streamed from the connection instead of being parsed at once. It can
+// not be combined with MultipartForm, FormFile or PostForm.
+func (c *Context) MultipartReader() (*multipart.Reader, error) {
+	return c.req.MultipartReader()
+}
+
+// EachPart streams the parts of a multipart body to fn one after another,
+// e.g. to pipe large uploads to disk or hash them without buffering:
+//
+//	err := c.EachPart(func(p *multipart.Part) error {
+//		if p.FileName() == "" {
+//			return nil
+//		}
+//		h := sha256.New()
+//		_, err := io.Copy(h, p)
+//		return err
+//	})
+//
+// A part is only valid during the call of fn. It stops at the first error
+// returned by fn, which is returned. The size of the body is limited by
+// Server.MaxBodySize.
+func (c *Context) EachPart(fn func(p *multipart.Part) error) error {
+	reader, err := c.MultipartReader()
+	if err != nil {
+		return err
+	}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = fn(part)
+		part.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package gweb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func multipartRequest(t *testing.T, fields map[string]string, files map[string]string) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range fields {
+		assert.NoError(t, mw.WriteField(k, v))
+	}
+	for name, content := range files {
+		fw, err := mw.CreateFormFile(name, name+".txt")
+		assert.NoError(t, err)
+		fw.Write([]byte(content))
+	}
+	assert.NoError(t, mw.Close())
+
+	req := httptest.NewRequest(MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestContextFormFile(t *testing.T) {
+	dir := t.TempDir()
+	s := NewServer()
+	s.POST("/upload", func(c *Context) {
+		fh, err := c.FormFile("doc")
+		assert.NoError(t, err)
+		assert.Equal(t, "doc.txt", fh.Filename)
+		assert.NoError(t, c.SaveUploadedFile(fh, filepath.Join(dir, "sub", fh.Filename)))
+
+		_, err = c.FormFile("missing")
+		assert.Equal(t, http.ErrMissingFile, err)
+
+		form, err := c.MultipartForm()
+		assert.NoError(t, err)
+		assert.Len(t, form.File, 1)
+		assert.Equal(t, "bob", c.PostForm("name"))
+	})
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, multipartRequest(t, map[string]string{"name": "bob"}, map[string]string{"doc": "hello"}))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "sub", "doc.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(content))
+}
+
+func TestBindMultipartMemory(t *testing.T) {
+	s := NewServer()
+	MaxMultipartMemoryOption(1)(s)
+	type upload struct {
+		Name string `form:"name"`
+	}
+	s.POST("/upload", func(c *Context) {
+		var u upload
+		assert.NoError(t, c.Bind(&u))
+		assert.Equal(t, "bob", u.Name)
+
+		// the file does not fit in memory and was stored in a temporary file.
+		f, err := c.Request().MultipartForm.File["doc"][0].Open()
+		assert.NoError(t, err)
+		defer f.Close()
+		_, onDisk := f.(*os.File)
+		assert.True(t, onDisk)
+	})
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, multipartRequest(t, map[string]string{"name": "bob"}, map[string]string{"doc": "hello"}))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestContextEachPart(t *testing.T) {
+	s := NewServer()
+	hashes := map[string]string{}
+	s.POST("/upload", func(c *Context) {
+		err := c.EachPart(func(p *multipart.Part) error {
+			h := sha256.New()
+			if _, err := io.Copy(h, p); err != nil {
+				return err
+			}
+			hashes[p.FormName()] = hex.EncodeToString(h.Sum(nil))
+			return nil
+		})
+		assert.NoError(t, err)
+	})
+
+	s.ServeHTTP(httptest.NewRecorder(), multipartRequest(t, map[string]string{"name": "bob"}, map[string]string{"doc": "hello"}))
+	sum := sha256.Sum256([]byte("hello"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), hashes["doc"])
+	assert.Len(t, hashes, 2)
+
+	errStop := errors.New("stop")
+	s.POST("/stop", func(c *Context) {
+		assert.Equal(t, errStop, c.EachPart(func(p *multipart.Part) error { return errStop }))
+	})
+	req := multipartRequest(t, nil, map[string]string{"doc": "hello"})
+	req.URL.Path = "/stop"
+	s.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestServerMaxBodySize(t *testing.T) {
+	s := NewServer()
+	MaxBodySizeOption(16)(s)
+	MaxMultipartMemoryOption(8)(s)
+	assert.Equal(t, int64(8), s.MaxMultipartMemory)
+
+	var uploadErr error
+	s.POST("/upload", func(c *Context) {
+		uploadErr = c.EachPart(func(p *multipart.Part) error {
+			_, err := io.Copy(ioutil.Discard, p)
+			return err
+		})
+	})
+
+	req := multipartRequest(t, nil, map[string]string{"doc": strings.Repeat("x", 1024)})
+	s.ServeHTTP(httptest.NewRecorder(), req)
+	var maxErr *http.MaxBytesError
+	assert.True(t, errors.As(uploadErr, &maxErr))
+	assert.Equal(t, int64(16), maxErr.Limit)
+}
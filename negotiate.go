@@ -0,0 +1,164 @@
+package gweb
+
+import (
+	"fmt"
+	"github.com/chen-zyc/gweb/binding"
+	"github.com/chen-zyc/gweb/render"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	MIMEJSON     = binding.MIMEJSON
+	MIMEXML      = binding.MIMEXML
+	MIMEXML2     = binding.MIMEXML2
+	MIMEYAML     = binding.MIMEYAML
+	MIMETOML     = binding.MIMETOML
+	MIMEMsgPack  = binding.MIMEMsgPack
+	MIMEProtoBuf = binding.MIMEProtoBuf
+	MIMEHTML     = "text/html"
+	MIMEPlain    = "text/plain"
+)
+
+// Negotiate describes the formats a handler can respond with, see
+// Context.Negotiate.
+type Negotiate struct {
+	// Offered lists the MIME types in order of preference, used to break
+	// ties between types the client accepts equally.
+	Offered []string
+	// HTMLName is the name of the template rendered for text/html.
+	HTMLName string
+	// The data rendered for each format, Data when it is nil.
+	HTMLData interface{}
+	JSONData interface{}
+	XMLData  interface{}
+	Data     interface{}
+}
+
+func (n Negotiate) data(specific interface{}) interface{} {
+	if specific != nil {
+		return specific
+	}
+	return n.Data
+}
+
+// Negotiate renders the data in the offered format preferred by the Accept
+// header of the request. HTML and the MIME types registered with
+// render.Register (JSON, XML and plain text, plus the formats of the
+// imported codec packages) can be offered. When the client accepts none of
+// them, a 406 response listing the offered types is written instead.
+func (c *Context) Negotiate(code int, config Negotiate) {
+	c.resp.Header().Add("Vary", "Accept")
+	switch format := filterFlags(c.NegotiateFormat(config.Offered...)); format {
+	case MIMEHTML:
+		c.HTML(code, config.HTMLName, config.data(config.HTMLData))
+	case "":
+		c.String(http.StatusNotAcceptable, "%s\nacceptable types: %s\n",
+			http.StatusText(http.StatusNotAcceptable), strings.Join(config.Offered, ", "))
+	default:
+		renderer, ok := render.Lookup(format)
+		if !ok {
+			panic(fmt.Sprintf("gweb: the offered format '%s' can not be rendered", format))
+		}
+		data := config.Data
+		switch format {
+		case MIMEJSON:
+			data = config.data(config.JSONData)
+		case MIMEXML, MIMEXML2:
+			data = config.data(config.XMLData)
+		}
+		c.Status(code)
+		c.Render(renderer(data))
+	}
+}
+
+// NegotiateFormat returns the offered MIME type preferred by the Accept
+// header, or "" when the client accepts none of them. The first offer is
+// returned when the request has no Accept header.
+func (c *Context) NegotiateFormat(offered ...string) string {
+	return negotiateFormat(strings.Join(c.req.Header.Values("Accept"), ","), offered)
+}
+
+// acceptRange is a media range of the Accept header, e.g. "text/*;q=0.5".
+type acceptRange struct {
+	typ, subtype string
+	q            float64
+}
+
+// parseAccept returns the media ranges of an Accept header, the malformed
+// ones are skipped.
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		mediaRange := strings.ToLower(strings.TrimSpace(params[0]))
+		if mediaRange == "*" { // sent by some old clients
+			mediaRange = "*/*"
+		}
+		typ, subtype, ok := strings.Cut(mediaRange, "/")
+		if !ok || typ == "" || subtype == "" || (typ == "*" && subtype != "*") {
+			continue
+		}
+
+		r := acceptRange{typ: typ, subtype: subtype, q: 1}
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.ToLower(strings.TrimSpace(name)) != "q" {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || q < 0 || q > 1 {
+				q = 0
+			}
+			r.q = q
+			break
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// match returns how specifically the range matches the media type: 3 for
+// the exact type, 2 for "type/*", 1 for "*/*" and 0 when it does not.
+func (r acceptRange) match(typ, subtype string) int {
+	switch {
+	case r.typ == "*":
+		return 1
+	case r.typ != typ:
+		return 0
+	case r.subtype == "*":
+		return 2
+	case r.subtype == subtype:
+		return 3
+	}
+	return 0
+}
+
+// negotiateFormat picks the offer with the highest quality, the quality of
+// an offer being the one of the most specific range matching it (RFC 7231,
+// section 5.3.2). Ties are won by the earliest offer.
+func negotiateFormat(accept string, offered []string) string {
+	if len(offered) == 0 {
+		return ""
+	}
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		return offered[0]
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offered {
+		typ, subtype, _ := strings.Cut(strings.ToLower(filterFlags(offer)), "/")
+		q, specificity := 0.0, 0
+		for _, r := range ranges {
+			if s := r.match(typ, subtype); s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
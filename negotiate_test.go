@@ -0,0 +1,79 @@
+package gweb
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	offered := []string{MIMEJSON, MIMEXML, MIMEHTML}
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", MIMEJSON},
+		{"application/xml", MIMEXML},
+		{"text/*", MIMEHTML},
+		{"*/*", MIMEJSON},
+		{"*", MIMEJSON},
+		{"text/html, application/xml;q=0.9, */*;q=0.8", MIMEHTML},
+		{"application/json;q=0.5, application/xml", MIMEXML},
+		// the most specific range wins over the wildcards.
+		{"application/*;q=0.9, application/json;q=0.1, text/html;q=0.5", MIMEXML},
+		{"*/*;q=0.1, application/json;q=0", MIMEXML},
+		// ties are won by the preference of the server.
+		{"application/xml, application/json", MIMEJSON},
+		{"APPLICATION/XML; Q=1", MIMEXML},
+		{"image/png", ""},
+		{"application/json;q=0, */*;q=0", ""},
+		{"application/json;q=abc, text/html", MIMEHTML},
+		{"garbage", MIMEJSON},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, negotiateFormat(tt.accept, offered), tt.accept)
+	}
+	assert.Equal(t, "", negotiateFormat("*/*", nil))
+}
+
+func TestContextNegotiate(t *testing.T) {
+	type user struct {
+		Name string `xml:"name"`
+	}
+	s := NewServer()
+	s.GET("/user", func(c *Context) {
+		c.Negotiate(http.StatusOK, Negotiate{
+			Offered: []string{MIMEJSON, MIMEXML, MIMEPlain},
+			Data:    H{"name": "gopher"},
+			XMLData: user{"gopher"},
+		})
+	})
+
+	perform := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(MethodGet, "/user", nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w
+	}
+
+	w := perform("")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "{\"name\":\"gopher\"}\n", w.Body.String())
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+
+	w = perform("text/xml;q=0.2, application/xml;q=0.9, application/json;q=0.8")
+	assert.Equal(t, "application/xml; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "<user><name>gopher</name></user>", w.Body.String())
+
+	w = perform("text/*")
+	assert.Equal(t, "map[name:gopher]", w.Body.String())
+
+	w = perform("image/png, text/html")
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+	assert.Equal(t, "Not Acceptable\nacceptable types: application/json, application/xml, text/plain\n", w.Body.String())
+}
@@ -1,6 +1,11 @@
 package gweb
 
-import "bytes"
+import (
+	"bytes"
+	"github.com/chen-zyc/gweb/websocket"
+	"os"
+	"time"
+)
 
 type Option func(s *Server)
 
@@ -62,8 +67,69 @@ func LogoOption(printLogo bool, logo ...string) Option {
 	}
 }
 
+func ForwardedByClientIPOption(forwardedByClientIP bool) Option {
+	return func(s *Server) {
+		s.ForwardedByClientIP = forwardedByClientIP
+	}
+}
+
+func PrintRoutesOption(printRoutes bool) Option {
+	return func(s *Server) {
+		s.PrintRoutes = printRoutes
+	}
+}
+
+func MaxMultipartMemoryOption(maxMemory int64) Option {
+	return func(s *Server) {
+		s.MaxMultipartMemory = maxMemory
+	}
+}
+
+func MaxBodySizeOption(maxSize int64) Option {
+	return func(s *Server) {
+		s.MaxBodySize = maxSize
+	}
+}
+
+func SecureJSONPrefixOption(prefix string) Option {
+	return func(s *Server) {
+		s.SecureJSONPrefix = prefix
+	}
+}
+
+func WebSocketUpgraderOption(upgrader websocket.Upgrader) Option {
+	return func(s *Server) {
+		s.WebSocketUpgrader = upgrader
+	}
+}
+
+func DeferRouteErrorsOption(deferRouteErrors bool) Option {
+	return func(s *Server) {
+		s.DeferRouteErrors = deferRouteErrors
+	}
+}
+
 func NameOption(name string) Option {
 	return func(s *Server) {
 		s.name = name
 	}
 }
+
+// GracefulShutdownOption makes Run shut the server down gracefully when one
+// of the signals is received, waiting at most timeout for the in-flight
+// requests. Without signals, os.Interrupt and SIGTERM are used.
+func GracefulShutdownOption(timeout time.Duration, signals ...os.Signal) Option {
+	if len(signals) == 0 {
+		signals = defaultShutdownSignals
+	}
+	return func(s *Server) {
+		s.ShutdownTimeout = timeout
+		s.shutdownSignals = signals
+	}
+}
+
+func HookTimeoutOption(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.HookTimeout = timeout
+	}
+}
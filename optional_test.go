@@ -0,0 +1,140 @@
+package gweb
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestOptionalParams(t *testing.T) {
+	s := NewServer()
+	s.GET("/docs/:page?", paramsHandler("docs"))
+	s.GET("/archive/:year<int>?/:month?", paramsHandler("archive"))
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/docs", http.StatusOK, "docs []"},
+		{"/docs/intro", http.StatusOK, "docs [{page intro}]"},
+		{"/archive", http.StatusOK, "archive []"},
+		{"/archive/2020", http.StatusOK, "archive [{year 2020}]"},
+		{"/archive/2020/05", http.StatusOK, "archive [{year 2020} {month 05}]"},
+		{"/archive/last", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := performRequest(s, MethodGet, tt.path)
+		assert.Equal(t, tt.code, w.Code, tt.path)
+		if tt.body != "" {
+			assert.Equal(t, tt.body, w.Body.String(), tt.path)
+		}
+	}
+
+	_, _, fullPath, _ := s.loadTrees()[MethodGet].Find("/docs")
+	assert.Equal(t, "/docs/:page?", fullPath)
+
+	// the root is left when the first segment is optional.
+	root := NewServer()
+	root.GET("/:lang?", paramsHandler("lang"))
+	assert.Equal(t, "lang []", performRequest(root, MethodGet, "/").Body.String())
+	assert.Equal(t, "lang [{lang en}]", performRequest(root, MethodGet, "/en").Body.String())
+
+	w := performRequest(s, MethodGet, "/DOCS/intro")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/docs/intro", w.Header().Get("Location"))
+
+	w = performRequest(s, MethodGet, "/docs/")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/docs", w.Header().Get("Location"))
+
+	assert.Equal(t, "a handle is already registered for path '/docs'", panicReason(func() {
+		s.GET("/docs", emptyHandler)
+	}))
+	for path, msg := range map[string]string{
+		"/a/*all?":      "only parameters can be optional in path '/a/*all?'",
+		"/a?":           "only parameters can be optional in path '/a?'",
+		"/a/:id?x":      "'?' must end its path segment in path '/a/:id?x'",
+		"/a/x:id?":      "an optional parameter must be a whole path segment in path '/a/x:id?'",
+		"/a/:id?/b":     "only the last segments of a path can be optional in path '/a/:id?/b'",
+		"/a/:id?/:name": "only the last segments of a path can be optional in path '/a/:id?/:name'",
+	} {
+		assert.Equal(t, msg, panicReason(func() { NewServer().GET(path, emptyHandler) }), path)
+	}
+}
+
+func TestCatchAllSuffixes(t *testing.T) {
+	s := NewServer()
+	s.GET("/files/*path/raw", paramsHandler("raw"))
+	s.GET("/files/*path", paramsHandler("file"))
+	s.GET("/files/*path/raw/meta", paramsHandler("meta"))
+	s.GET("/blobs/*path/raw", paramsHandler("blob"))
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/files/a/b/raw", http.StatusOK, "raw [{path /a/b}]"},
+		{"/files/a/raw/meta", http.StatusOK, "meta [{path /a}]"},
+		{"/files/a/b", http.StatusOK, "file [{path /a/b}]"},
+		// the catch-all value can not be empty.
+		{"/files/raw", http.StatusOK, "file [{path /raw}]"},
+		{"/blobs/a/raw", http.StatusOK, "blob [{path /a}]"},
+		{"/blobs/raw", http.StatusNotFound, ""},
+		{"/blobs/a", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := performRequest(s, MethodGet, tt.path)
+		assert.Equal(t, tt.code, w.Code, tt.path)
+		if tt.body != "" {
+			assert.Equal(t, tt.body, w.Body.String(), tt.path)
+		}
+	}
+
+	_, _, fullPath, _ := s.loadTrees()[MethodGet].Find("/files/a/raw")
+	assert.Equal(t, "/files/*path/raw", fullPath)
+
+	w := performRequest(s, MethodGet, "/blobs/a/raw/")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/blobs/a/raw", w.Header().Get("Location"))
+
+	w = performRequest(s, MethodGet, "/BLOBS/A/RAW")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/blobs/A/raw", w.Header().Get("Location"))
+
+	assert.Equal(t, "a handle is already registered for path '/files/*path/raw'", panicReason(func() {
+		s.GET("/files/*path/raw", emptyHandler)
+	}))
+	assert.Panics(t, func() { s.GET("/files/*name/raw", emptyHandler) })
+	assert.Equal(t, "catch-all routes can only be followed by static segments in path '/a/*all/:id'", panicReason(func() {
+		NewServer().GET("/a/*all/:id", emptyHandler)
+	}))
+}
+
+func TestURLWithOptionalParams(t *testing.T) {
+	s := NewServer()
+	s.GET("/archive/:year<int>?/:month?", emptyHandler).Name("archive")
+	s.POST("/:lang?", emptyHandler).Name("home")
+	s.GET("/files/*path/raw", emptyHandler).Name("raw")
+
+	for _, tt := range []struct {
+		name   string
+		params []interface{}
+		url    string
+	}{
+		{"archive", nil, "/archive"},
+		{"archive", []interface{}{"year", 2020}, "/archive/2020"},
+		{"archive", []interface{}{"year", 2020, "month", 5}, "/archive/2020/5"},
+		{"home", nil, "/"},
+		{"home", []interface{}{"lang", "en"}, "/en"},
+		{"raw", []interface{}{"path", "/a b/c"}, "/files/a%20b/c/raw"},
+	} {
+		u, err := s.URL(tt.name, tt.params...)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.url, u)
+	}
+
+	_, err := s.URL("archive", "month", 5)
+	assert.EqualError(t, err, "gweb: missing parameter 'year' for path '/archive/:year<int>?/:month?'")
+}
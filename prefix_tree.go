@@ -48,16 +48,44 @@ type Node struct {
 	indices   string
 	children  []*Node
 	handle    Handlers
+	fullPath  string // the registered path of handle
 	priority  uint32
+
+	// constraint restricts the values matched by a param node. The param
+	// children of a node differ by their constraint: the constrained ones
+	// are tried in registration order, then the unconstrained one.
+	constraint *paramConstraint
+	// suffixes are the static ends of the routes continuing after a
+	// catch-all, e.g. "/raw" for "/files/*path/raw", longest first.
+	suffixes []*catchAllSuffix
+}
+
+type catchAllSuffix struct {
+	suffix   string
+	handle   Handlers
+	fullPath string
 }
 
 var _ Router = (*Node)(nil)
 
 func NewPrefixTree() *Node { return &Node{} }
 
+// clone returns a deep copy of the tree, which can be modified while n is
+// being read. The handles and constraints are shared, they are never
+// modified.
+func (n *Node) clone() *Node {
+	c := *n
+	c.children = make([]*Node, len(n.children))
+	for i, child := range n.children {
+		c.children[i] = child.clone()
+	}
+	c.suffixes = append([]*catchAllSuffix(nil), n.suffixes...)
+	return &c
+}
+
 func (n *Node) Add(path string, handler Handlers) { n.addRoute(path, handler) }
 
-func (n *Node) Find(path string) (handler Handlers, params Params, tsr bool) {
+func (n *Node) Find(path string) (handler Handlers, params Params, fullPath string, tsr bool) {
 	return n.getValue(path)
 }
 
@@ -89,10 +117,17 @@ func (n *Node) incrementChildPrio(pos int) int {
 	return newPos
 }
 
-// addRoute adds a Node with the given handle to the path.
+// addRoute adds a Node with the given handle to the path, and to the paths
+// without its optional segments.
 // Not concurrency-safe!
 func (n *Node) addRoute(path string, handle Handlers) {
-	fullPath := path
+	for _, rp := range parseRoutePath(path).expand() {
+		n.addRoutePath(rp, handle)
+	}
+}
+
+func (n *Node) addRoutePath(rp *routePath, handle Handlers) {
+	fullPath, path := rp.full, rp.clean
 	n.priority++
 	numParams := countParams(path)
 
@@ -123,6 +158,7 @@ func (n *Node) addRoute(path string, handle Handlers) {
 					indices:   n.indices,
 					children:  n.children,
 					handle:    n.handle,
+					fullPath:  n.fullPath,
 					priority:  n.priority - 1,
 				}
 
@@ -138,6 +174,7 @@ func (n *Node) addRoute(path string, handle Handlers) {
 				n.indices = string([]byte{n.path[i]})
 				n.path = path[:i]
 				n.handle = nil
+				n.fullPath = ""
 				n.wildChild = false
 			}
 
@@ -146,7 +183,21 @@ func (n *Node) addRoute(path string, handle Handlers) {
 				path = path[i:]
 
 				if n.wildChild {
-					n = n.children[0]
+					parent, constraint := n, rp.constraintAt(path)
+					n = nil
+					for _, child := range parent.children {
+						if child.constraint.String() == constraint.String() {
+							n = child
+							break
+						}
+					}
+					if n == nil {
+						if path[0] == ':' && parent.children[0].nType == param {
+							parent.addParamSibling(numParams, path, rp, handle)
+							return
+						}
+						n = parent.children[0] // reported as a conflict below
+					}
 					n.priority++
 
 					// Update maxParams of the child Node
@@ -159,6 +210,10 @@ func (n *Node) addRoute(path string, handle Handlers) {
 					if len(path) >= len(n.path) && n.path == path[:len(n.path)] &&
 						// Check for longer wildcard, e.g. :name and :names
 						(len(n.path) >= len(path) || path[len(n.path)] == '/') {
+						if n.nType == catchAll {
+							n.addCatchAllSuffix(path[len(n.path):], fullPath, handle)
+							return
+						}
 						continue walk
 					} else {
 						// Wildcard conflict
@@ -168,7 +223,7 @@ func (n *Node) addRoute(path string, handle Handlers) {
 						} else {
 							pathSeg = strings.SplitN(path, "/", 2)[0]
 						}
-						prefix := fullPath[:strings.Index(fullPath, pathSeg)] + n.path
+						prefix := rp.clean[:strings.Index(rp.clean, pathSeg)] + n.path
 						panic("'" + pathSeg +
 							"' in new path '" + fullPath +
 							"' conflicts with existing wildcard '" + n.path +
@@ -206,7 +261,7 @@ func (n *Node) addRoute(path string, handle Handlers) {
 					n.incrementChildPrio(len(n.indices) - 1)
 					n = child
 				}
-				n.insertChild(numParams, path, fullPath, handle)
+				n.insertChild(numParams, path, rp, handle)
 				return
 
 			} else if i == len(path) { // Make Node a (in-path) leaf
@@ -214,16 +269,77 @@ func (n *Node) addRoute(path string, handle Handlers) {
 					panic("a handle is already registered for path '" + fullPath + "'")
 				}
 				n.handle = handle
+				n.fullPath = fullPath
 			}
 			return
 		}
 	} else { // Empty tree
-		n.insertChild(numParams, path, fullPath, handle)
+		n.insertChild(numParams, path, rp, handle)
 		n.nType = root
 	}
 }
 
-func (n *Node) insertChild(numParams uint8, path, fullPath string, handle Handlers) {
+// addParamSibling adds a param child with a constraint the other param
+// children do not have, followed by the rest of the path.
+func (n *Node) addParamSibling(numParams uint8, path string, rp *routePath, handle Handlers) {
+	tmp := &Node{}
+	tmp.insertChild(numParams, path, rp, handle)
+	child := tmp.children[0]
+
+	last := len(n.children) - 1
+	if child.constraint != nil && n.children[last].constraint == nil {
+		// keep the unconstrained param last.
+		n.children = append(n.children[:last], child, n.children[last])
+	} else {
+		n.children = append(n.children, child)
+	}
+	if child.maxParams > n.maxParams {
+		n.maxParams = child.maxParams
+	}
+}
+
+// addCatchAllSuffix sets the handle of the catch-all node n for the paths
+// ending with suffix, or for any path if suffix is empty.
+func (n *Node) addCatchAllSuffix(suffix, fullPath string, handle Handlers) {
+	if suffix == "" {
+		if n.handle != nil {
+			panic("a handle is already registered for path '" + fullPath + "'")
+		}
+		n.handle = handle
+		n.fullPath = fullPath
+		return
+	}
+	if suffix[0] != '/' {
+		panic("a catch-all must be followed by '/' in path '" + fullPath + "'")
+	}
+
+	i := 0
+	for ; i < len(n.suffixes); i++ {
+		if n.suffixes[i].suffix == suffix {
+			panic("a handle is already registered for path '" + fullPath + "'")
+		}
+		if len(n.suffixes[i].suffix) < len(suffix) {
+			break
+		}
+	}
+	n.suffixes = append(n.suffixes, nil)
+	copy(n.suffixes[i+1:], n.suffixes[i:])
+	n.suffixes[i] = &catchAllSuffix{suffix: suffix, handle: handle, fullPath: fullPath}
+}
+
+// matchSuffix returns the longest suffix ending path after a non-empty
+// catch-all value.
+func (n *Node) matchSuffix(path string) *catchAllSuffix {
+	for _, s := range n.suffixes {
+		if len(path) > len(s.suffix) && strings.HasSuffix(path, s.suffix) {
+			return s
+		}
+	}
+	return nil
+}
+
+func (n *Node) insertChild(numParams uint8, path string, rp *routePath, handle Handlers) {
+	fullPath := rp.full
 	var offset int // already handled bytes of the path
 
 	// find prefix until first wildcard (beginning with ':'' or '*'')
@@ -266,8 +382,9 @@ func (n *Node) insertChild(numParams uint8, path, fullPath string, handle Handle
 			}
 
 			child := &Node{
-				nType:     param,
-				maxParams: numParams,
+				nType:      param,
+				maxParams:  numParams,
+				constraint: rp.constraintAt(path[i:]),
 			}
 			n.children = []*Node{child}
 			n.wildChild = true
@@ -290,8 +407,8 @@ func (n *Node) insertChild(numParams uint8, path, fullPath string, handle Handle
 			}
 
 		} else { // catchAll
-			if end != max || numParams > 1 {
-				panic("catch-all routes are only allowed at the end of the path in path '" + fullPath + "'")
+			if numParams > 1 {
+				panic("catch-all routes can only be followed by static segments in path '" + fullPath + "'")
 			}
 
 			if len(n.path) > 0 && n.path[len(n.path)-1] == '/' {
@@ -319,13 +436,13 @@ func (n *Node) insertChild(numParams uint8, path, fullPath string, handle Handle
 
 			// second Node: Node holding the variable
 			child = &Node{
-				path:      path[i:],
+				path:      path[i:end],
 				nType:     catchAll,
 				maxParams: 1,
-				handle:    handle,
 				priority:  1,
 			}
 			n.children = []*Node{child}
+			child.addCatchAllSuffix(path[end:], fullPath, handle)
 
 			return
 		}
@@ -334,14 +451,22 @@ func (n *Node) insertChild(numParams uint8, path, fullPath string, handle Handle
 	// insert remaining path part and handle to the leaf
 	n.path = path[offset:]
 	n.handle = handle
+	n.fullPath = fullPath
 }
 
 // Returns the handle registered with the given path (key). The values of
 // wildcards are saved to a map.
 // If no handle can be found, a TSR (trailing slash redirect) recommendation is
 // made if a handle exists with an extra (without the) trailing slash for the
-// given path.
-func (n *Node) getValue(path string) (handle Handlers, p Params, tsr bool) {
+// given path. fullPath is the registered path of the handle.
+func (n *Node) getValue(path string) (handle Handlers, p Params, fullPath string, tsr bool) {
+	return n.lookup(path, nil)
+}
+
+// lookup is getValue with the params of the parent nodes. A param segment
+// is matched by the first param child accepting it whose subtree has a
+// handle for the rest of the path.
+func (n *Node) lookup(path string, p Params) (handle Handlers, _ Params, fullPath string, tsr bool) {
 walk: // outer loop for walking the tree
 	for {
 		if len(path) > len(n.path) {
@@ -363,13 +488,12 @@ walk: // outer loop for walking the tree
 					// We can recommend to redirect to the same URL without a
 					// trailing slash if a leaf exists for that path.
 					tsr = (path == "/" && n.handle != nil)
-					return
+					return nil, p, "", tsr
 
 				}
 
 				// handle wildcard child
-				n = n.children[0]
-				switch n.nType {
+				switch n.children[0].nType {
 				case param:
 					// find param end (either '/' or path end)
 					end := 0
@@ -377,53 +501,39 @@ walk: // outer loop for walking the tree
 						end++
 					}
 
-					// save param value
-					if p == nil {
-						// lazy allocation
-						p = make(Params, 0, n.maxParams)
-					}
-					i := len(p)
-					p = p[:i+1] // expand slice within preallocated capacity
-					p[i].Key = n.path[1:]
-					p[i].Value = path[:end]
-
-					// we need to go deeper!
-					if end < len(path) {
-						if len(n.children) > 0 {
-							path = path[end:]
-							n = n.children[0]
-							continue walk
+					for _, child := range n.children {
+						if !child.accepts(path[:end]) {
+							continue
 						}
-
-						// ... but we can't
-						tsr = (len(path) == end+1)
-						return
+						h, cp, fp, t := child.paramValue(path, end, p)
+						if h != nil {
+							return h, cp, fp, false
+						}
+						tsr = tsr || t
 					}
+					return nil, p, "", tsr
 
-					if handle = n.handle; handle != nil {
-						return
-					} else if len(n.children) == 1 {
-						// No handle found. Check if a handle for this path + a
-						// trailing slash exists for TSR recommendation
-						n = n.children[0]
-						tsr = (n.path == "/" && n.handle != nil)
+				case catchAll:
+					n = n.children[0]
+					handle, fullPath, value := n.handle, n.fullPath, path
+					if s := n.matchSuffix(path); s != nil {
+						handle, fullPath, value = s.handle, s.fullPath, path[:len(path)-len(s.suffix)]
+					} else if handle == nil {
+						// recommend the path with or without a trailing
+						// slash if it ends with a suffix.
+						tsr = n.matchSuffix(path+"/") != nil ||
+							(path[len(path)-1] == '/' && n.matchSuffix(path[:len(path)-1]) != nil)
+						return nil, p, "", tsr
 					}
 
-					return
-
-				case catchAll:
 					// save param value
 					if p == nil {
 						// lazy allocation
 						p = make(Params, 0, n.maxParams)
 					}
-					i := len(p)
-					p = p[:i+1] // expand slice within preallocated capacity
-					p[i].Key = n.path[2:]
-					p[i].Value = path
+					p = append(p, Param{Key: n.path[2:], Value: value})
 
-					handle = n.handle
-					return
+					return handle, p, fullPath, false
 
 				default:
 					panic("invalid Node type")
@@ -433,12 +543,11 @@ walk: // outer loop for walking the tree
 			// We should have reached the Node containing the handle.
 			// Check if this Node has a handle registered.
 			if handle = n.handle; handle != nil {
-				return
+				return handle, p, n.fullPath, false
 			}
 
 			if path == "/" && n.wildChild && n.nType != root {
-				tsr = true
-				return
+				return nil, p, "", true
 			}
 
 			// No handle found. Check if a handle for this path + a
@@ -448,11 +557,11 @@ walk: // outer loop for walking the tree
 					n = n.children[i]
 					tsr = (len(n.path) == 1 && n.handle != nil) ||
 						(n.nType == catchAll && n.children[0].handle != nil)
-					return
+					return nil, p, "", tsr
 				}
 			}
 
-			return
+			return nil, p, "", false
 		}
 
 		// Nothing found. We can recommend to redirect to the same URL with an
@@ -460,8 +569,39 @@ walk: // outer loop for walking the tree
 		tsr = (path == "/") ||
 			(len(n.path) == len(path)+1 && n.path[len(path)] == '/' &&
 				path == n.path[:len(n.path)-1] && n.handle != nil)
-		return
+		return nil, p, "", tsr
+	}
+}
+
+// paramValue matches the param node n against path, whose first end bytes
+// are the value of the param.
+func (n *Node) paramValue(path string, end int, p Params) (handle Handlers, _ Params, fullPath string, tsr bool) {
+	// save param value
+	if p == nil {
+		// lazy allocation
+		p = make(Params, 0, n.maxParams)
+	}
+	p = append(p, Param{Key: n.path[1:], Value: path[:end]})
+
+	// we need to go deeper!
+	if end < len(path) {
+		if len(n.children) > 0 {
+			return n.children[0].lookup(path[end:], p)
+		}
+
+		// ... but we can't
+		return nil, p, "", len(path) == end+1
 	}
+
+	if handle = n.handle; handle != nil {
+		return handle, p, n.fullPath, false
+	} else if len(n.children) == 1 {
+		// No handle found. Check if a handle for this path + a
+		// trailing slash exists for TSR recommendation
+		n = n.children[0]
+		tsr = (n.path == "/" && n.handle != nil)
+	}
+	return nil, p, "", tsr
 }
 
 // Makes a case-insensitive lookup of the given path and tries to find a handler.
@@ -582,8 +722,7 @@ walk: // outer loop for walking the tree
 				return ciPath, (fixTrailingSlash && path == "/" && n.handle != nil)
 			}
 
-			n = n.children[0]
-			switch n.nType {
+			switch n.children[0].nType {
 			case param:
 				// find param end (either '/' or path end)
 				k := 0
@@ -591,42 +730,38 @@ walk: // outer loop for walking the tree
 					k++
 				}
 
-				// add param value to case insensitive path
-				ciPath = append(ciPath, path[:k]...)
-
-				// we need to go deeper!
-				if k < len(path) {
-					if len(n.children) > 0 {
-						// continue with child Node
-						n = n.children[0]
-						loNPath = strings.ToLower(n.path)
-						loPath = loPath[k:]
-						path = path[k:]
+				for _, child := range n.children {
+					if !child.accepts(path[:k]) {
 						continue
 					}
-
-					// ... but we can't
-					if fixTrailingSlash && len(path) == k+1 {
-						return ciPath, true
+					if out, found := child.findCaseInsensitiveParam(
+						path, loPath, k, ciPath, rb, fixTrailingSlash,
+					); found {
+						return out, true
 					}
-					return ciPath, false
 				}
+				return ciPath, false
 
-				if n.handle != nil {
-					return ciPath, true
-				} else if fixTrailingSlash && len(n.children) == 1 {
-					// No handle found. Check if a handle for this path + a
-					// trailing slash exists
-					n = n.children[0]
-					if n.path == "/" && n.handle != nil {
-						return append(ciPath, '/'), true
+			case catchAll:
+				n = n.children[0]
+				candidates := []string{loPath}
+				if fixTrailingSlash {
+					candidates = append(candidates, loPath+"/", strings.TrimSuffix(loPath, "/"))
+				}
+				for _, s := range n.suffixes {
+					loSuffix := strings.ToLower(s.suffix)
+					for _, c := range candidates {
+						if len(c) > len(loSuffix) && strings.HasSuffix(c, loSuffix) {
+							value := path[:len(c)-len(loSuffix)]
+							return append(append(ciPath, value...), s.suffix...), true
+						}
 					}
 				}
+				if n.handle != nil {
+					return append(ciPath, path...), true
+				}
 				return ciPath, false
 
-			case catchAll:
-				return append(ciPath, path...), true
-
 			default:
 				panic("invalid Node type")
 			}
@@ -668,3 +803,38 @@ walk: // outer loop for walking the tree
 	}
 	return ciPath, false
 }
+
+// findCaseInsensitiveParam is the case-insensitive lookup of the param node
+// n, whose value is the first k bytes of path.
+func (n *Node) findCaseInsensitiveParam(path, loPath string, k int, ciPath []byte, rb [4]byte, fixTrailingSlash bool) ([]byte, bool) {
+	// add param value to case insensitive path
+	ciPath = append(ciPath, path[:k]...)
+
+	// we need to go deeper!
+	if k < len(path) {
+		if len(n.children) > 0 {
+			// continue with child Node
+			return n.children[0].findCaseInsensitivePathRec(
+				path[k:], loPath[k:], ciPath, rb, fixTrailingSlash,
+			)
+		}
+
+		// ... but we can't
+		if fixTrailingSlash && len(path) == k+1 {
+			return ciPath, true
+		}
+		return ciPath, false
+	}
+
+	if n.handle != nil {
+		return ciPath, true
+	} else if fixTrailingSlash && len(n.children) == 1 {
+		// No handle found. Check if a handle for this path + a
+		// trailing slash exists
+		n = n.children[0]
+		if n.path == "/" && n.handle != nil {
+			return append(ciPath, '/'), true
+		}
+	}
+	return ciPath, false
+}
@@ -0,0 +1,67 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Renderer returns the Render writing obj in a format.
+type Renderer func(obj interface{}) Render
+
+// renderers holds the renderers by MIME type. The codec packages, e.g.
+// github.com/chen-zyc/gweb/codec/yaml, register more formats when imported.
+var (
+	renderersMu sync.RWMutex
+	renderers   = map[string]Renderer{
+		"application/json": JSONRender,
+		"application/xml":  XMLRender,
+		"text/xml":         XMLRender,
+		"text/plain":       func(obj interface{}) Render { return StringRender("%v", obj) },
+	}
+)
+
+// Register sets the renderer used for the MIME type, given without
+// parameters, replacing the one already registered.
+func Register(mime string, r Renderer) {
+	renderersMu.Lock()
+	renderers[mime] = r
+	renderersMu.Unlock()
+}
+
+// Lookup returns the renderer registered for the MIME type.
+func Lookup(mime string) (r Renderer, ok bool) {
+	renderersMu.RLock()
+	r, ok = renderers[mime]
+	renderersMu.RUnlock()
+	return
+}
+
+// MIMETypes returns the sorted MIME types having a renderer.
+func MIMETypes() []string {
+	renderersMu.RLock()
+	types := make([]string, 0, len(renderers))
+	for mime := range renderers {
+		types = append(types, mime)
+	}
+	renderersMu.RUnlock()
+	sort.Strings(types)
+	return types
+}
+
+// ErrNoRenderer is returned by MIMERender when no renderer is registered for
+// the MIME type.
+var ErrNoRenderer = errors.New("render: no renderer registered")
+
+// MIMERender renders obj with the renderer registered for the MIME type.
+func MIMERender(mime string, obj interface{}) Render {
+	r, ok := Lookup(mime)
+	if !ok {
+		return RenderFunc(func(http.ResponseWriter) error {
+			return fmt.Errorf("%w for %s", ErrNoRenderer, mime)
+		})
+	}
+	return r(obj)
+}
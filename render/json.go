@@ -0,0 +1,114 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"unicode/utf8"
+)
+
+// ErrInvalidCallback is returned by JSONPRender when the callback is not a
+// JavaScript identifier or a dotted path of identifiers.
+var ErrInvalidCallback = errors.New("render: invalid JSONP callback")
+
+var callbackRegexp = regexp.MustCompile(`^[a-zA-Z_$][0-9a-zA-Z_$]*(\.[a-zA-Z_$][0-9a-zA-Z_$]*)*$`)
+
+// ValidCallback reports whether the JSONP callback can be written as is in
+// the response, e.g. "cb" or "jQuery.callbacks.cb_1".
+func ValidCallback(callback string) bool {
+	return len(callback) <= 128 && callbackRegexp.MatchString(callback)
+}
+
+// IndentedJSONRender writes obj as JSON indented with four spaces.
+func IndentedJSONRender(obj interface{}) Render {
+	return RenderFunc(func(w http.ResponseWriter) error {
+		writeContentType(w, "application/json; charset=utf-8")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		return enc.Encode(obj)
+	})
+}
+
+// SecureJSONRender writes obj as JSON after the prefix, e.g. "while(1);",
+// which prevents the response from being executed as a script by another
+// site. The clients strip the prefix before parsing the body.
+func SecureJSONRender(prefix string, obj interface{}) Render {
+	return RenderFunc(func(w http.ResponseWriter) error {
+		writeContentType(w, "application/json; charset=utf-8")
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if _, err = w.Write([]byte(prefix)); err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	})
+}
+
+// JSONPRender writes obj as JSON wrapped in a call to callback, or as plain
+// JSON if callback is empty. ErrInvalidCallback is returned before anything
+// is written if the callback is not valid.
+func JSONPRender(callback string, obj interface{}) Render {
+	return RenderFunc(func(w http.ResponseWriter) error {
+		if callback == "" {
+			return JSONRender(obj).Render(w)
+		}
+		if !ValidCallback(callback) {
+			return ErrInvalidCallback
+		}
+		writeContentType(w, "application/javascript; charset=utf-8")
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		// the leading comment prevents the body from starting with bytes
+		// chosen by the client.
+		_, err = fmt.Fprintf(w, "/**/%s(%s);", callback, data)
+		return err
+	})
+}
+
+// AsciiJSONRender writes obj as JSON with the non-ASCII characters escaped
+// as \uXXXX sequences.
+func AsciiJSONRender(obj interface{}) Render {
+	return RenderFunc(func(w http.ResponseWriter) error {
+		writeContentType(w, "application/json")
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		for len(data) > 0 {
+			r, size := utf8.DecodeRune(data)
+			switch {
+			case r < utf8.RuneSelf:
+				buf.WriteByte(data[0])
+			case r > 0xffff:
+				r -= 0x10000
+				fmt.Fprintf(&buf, `\u%04x\u%04x`, 0xd800+(r>>10), 0xdc00+(r&0x3ff))
+			default:
+				fmt.Fprintf(&buf, `\u%04x`, r)
+			}
+			data = data[size:]
+		}
+		buf.WriteByte('\n')
+		_, err = w.Write(buf.Bytes())
+		return err
+	})
+}
+
+// PureJSONRender writes obj as JSON without escaping <, > and & as JSONRender
+// does.
+func PureJSONRender(obj interface{}) Render {
+	return RenderFunc(func(w http.ResponseWriter) error {
+		writeContentType(w, "application/json; charset=utf-8")
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(obj)
+	})
+}
@@ -0,0 +1,78 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// SSEvent is a Server-Sent Event. Data is written as is if it is a string or
+// a []byte and encoded as JSON otherwise. Multi-line data is split into
+// several data fields. Empty fields are omitted.
+type SSEvent struct {
+	Event string
+	ID    string
+	// Retry is the reconnection time in milliseconds the client should use.
+	Retry uint
+	Data  interface{}
+}
+
+var sseReplacer = strings.NewReplacer("\n", "", "\r", "")
+
+func (e SSEvent) Render(w http.ResponseWriter) error {
+	header := w.Header()
+	if header.Get("Content-Type") == "" {
+		writeContentType(w, "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+	}
+
+	var buf bytes.Buffer
+	if e.ID != "" {
+		buf.WriteString("id: ")
+		buf.WriteString(sseReplacer.Replace(e.ID))
+		buf.WriteByte('\n')
+	}
+	if e.Event != "" {
+		buf.WriteString("event: ")
+		buf.WriteString(sseReplacer.Replace(e.Event))
+		buf.WriteByte('\n')
+	}
+	if e.Retry > 0 {
+		fmt.Fprintf(&buf, "retry: %d\n", e.Retry)
+	}
+
+	data, err := sseData(e.Data)
+	if err != nil {
+		return err
+	}
+	if data != "" || e.Data != nil {
+		// EventSource ends a line at "\r\n", "\r" or "\n".
+		data = strings.ReplaceAll(data, "\r\n", "\n")
+		data = strings.ReplaceAll(data, "\r", "\n")
+		for _, line := range strings.Split(data, "\n") {
+			buf.WriteString("data: ")
+			buf.WriteString(line)
+			buf.WriteByte('\n')
+		}
+	}
+	buf.WriteByte('\n')
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func sseData(data interface{}) (string, error) {
+	switch d := data.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return d, nil
+	case []byte:
+		return string(d), nil
+	default:
+		b, err := json.Marshal(d)
+		return string(b), err
+	}
+}
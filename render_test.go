@@ -0,0 +1,151 @@
+package gweb
+
+import (
+	"errors"
+	"fmt"
+	_ "github.com/chen-zyc/gweb/codec/msgpack"
+	_ "github.com/chen-zyc/gweb/codec/protobuf"
+	_ "github.com/chen-zyc/gweb/codec/toml"
+	_ "github.com/chen-zyc/gweb/codec/yaml"
+	"github.com/chen-zyc/gweb/render"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestContextJSONRenders(t *testing.T) {
+	obj := H{"html": "<b>&</b>", "name": "café 🍺"}
+	s := NewServer()
+	s.GET("/indented", func(c *Context) { c.IndentedJSON(http.StatusOK, H{"a": []int{1}}) })
+	s.GET("/secure", func(c *Context) { c.SecureJSON(http.StatusOK, []int{1, 2}) })
+	s.GET("/jsonp", func(c *Context) { c.JSONP(http.StatusOK, obj) })
+	s.GET("/ascii", func(c *Context) { c.AsciiJSON(http.StatusOK, obj) })
+	s.GET("/pure", func(c *Context) { c.PureJSON(http.StatusOK, obj) })
+
+	w := performRequest(s, MethodGet, "/indented")
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "{\n    \"a\": [\n        1\n    ]\n}\n", w.Body.String())
+
+	w = performRequest(s, MethodGet, "/secure")
+	assert.Equal(t, "while(1);[1,2]\n", w.Body.String())
+	SecureJSONPrefixOption(")]}',\n")(s)
+	w = performRequest(s, MethodGet, "/secure")
+	assert.Equal(t, ")]}',\n[1,2]\n", w.Body.String())
+
+	w = performRequest(s, MethodGet, "/jsonp?callback=app.cb_1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/javascript; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `/**/app.cb_1({"html":"\u003cb\u003e\u0026\u003c/b\u003e","name":"café 🍺"});`, w.Body.String())
+	w = performRequest(s, MethodGet, "/jsonp")
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	w = performRequest(s, MethodGet, "/jsonp?callback=alert(1)//")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = performRequest(s, MethodGet, "/ascii")
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"html":"\u003cb\u003e\u0026\u003c/b\u003e","name":"caf\u00e9 \ud83c\udf7a"}`+"\n", w.Body.String())
+
+	w = performRequest(s, MethodGet, "/pure")
+	assert.Equal(t, `{"html":"<b>&</b>","name":"café 🍺"}`+"\n", w.Body.String())
+}
+
+func TestValidCallback(t *testing.T) {
+	for _, cb := range []string{"cb", "$", "_a1", "jQuery.callbacks.cb_1"} {
+		assert.True(t, render.ValidCallback(cb), cb)
+	}
+	for _, cb := range []string{"", "1cb", "a.", "a..b", "a-b", "alert(1)", "a;b"} {
+		assert.False(t, render.ValidCallback(cb), cb)
+	}
+	err := render.JSONPRender("a b", 1).Render(nil)
+	assert.Equal(t, render.ErrInvalidCallback, err)
+}
+
+func TestContextCodecRenders(t *testing.T) {
+	type point struct {
+		A int `yaml:"a" toml:"a" msgpack:"a"`
+		B int `yaml:"b" toml:"b" msgpack:"b"`
+	}
+	s := NewServer()
+	s.GET("/yaml", func(c *Context) { c.YAML(http.StatusOK, point{1, 2}) })
+	s.GET("/toml", func(c *Context) { c.TOML(http.StatusOK, point{1, 2}) })
+	s.GET("/msgpack", func(c *Context) { c.MsgPack(http.StatusOK, point{1, 2}) })
+	s.GET("/protobuf", func(c *Context) { c.ProtoBuf(http.StatusOK, wrapperspb.String("hello")) })
+	s.POST("/bind", func(c *Context) {
+		var p point
+		if err := c.Bind(&p); err != nil {
+			c.String(http.StatusBadRequest, "%s", err.Error())
+			return
+		}
+		c.String(http.StatusOK, "%d,%d", p.A, p.B)
+	})
+
+	w := performRequest(s, MethodGet, "/yaml")
+	assert.Equal(t, "application/yaml; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "a: 1\nb: 2\n", w.Body.String())
+
+	w = performRequest(s, MethodGet, "/toml")
+	assert.Equal(t, "application/toml; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "a = 1\nb = 2\n", w.Body.String())
+
+	w = performRequest(s, MethodGet, "/msgpack")
+	assert.Equal(t, "application/x-msgpack", w.Header().Get("Content-Type"))
+	var p point
+	assert.NoError(t, msgpack.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, point{1, 2}, p)
+
+	w = performRequest(s, MethodGet, "/protobuf")
+	assert.Equal(t, "application/x-protobuf", w.Header().Get("Content-Type"))
+	msg := &wrapperspb.StringValue{}
+	assert.NoError(t, proto.Unmarshal(w.Body.Bytes(), msg))
+	assert.Equal(t, "hello", msg.GetValue())
+
+	// Bind picks the binding registered for the Content-Type.
+	for contentType, body := range map[string]string{
+		MIMEYAML: "a: 3\nb: 4\n",
+		MIMETOML: "a = 3\nb = 4\n",
+		MIMEJSON: `{"A":3,"B":4}`,
+	} {
+		req := httptest.NewRequest(MethodPost, "/bind", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		w = httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		assert.Equal(t, "3,4", w.Body.String(), contentType)
+	}
+}
+
+func TestRenderRegistry(t *testing.T) {
+	err := render.MIMERender("application/vnd.csv", nil).Render(httptest.NewRecorder())
+	assert.True(t, errors.Is(err, render.ErrNoRenderer))
+
+	render.Register("application/vnd.csv", func(obj interface{}) render.Render {
+		return render.RenderFunc(func(w http.ResponseWriter) error {
+			w.Header().Set("Content-Type", "application/vnd.csv")
+			_, err := fmt.Fprintf(w, "%v,%v\n", obj.([]int)[0], obj.([]int)[1])
+			return err
+		})
+	})
+	assert.Contains(t, render.MIMETypes(), "application/vnd.csv")
+
+	s := NewServer()
+	s.GET("/data", func(c *Context) {
+		c.Negotiate(http.StatusOK, Negotiate{
+			Offered: []string{MIMEJSON, MIMEYAML, "application/vnd.csv"},
+			Data:    []int{1, 2},
+		})
+	})
+	req := httptest.NewRequest(MethodGet, "/data", nil)
+	req.Header.Set("Accept", "application/vnd.csv")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	assert.Equal(t, "1,2\n", w.Body.String())
+
+	req.Header.Set("Accept", "application/yaml, application/json;q=0.9")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	assert.Equal(t, "- 1\n- 2\n", w.Body.String())
+}
@@ -0,0 +1,125 @@
+package gweb
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+)
+
+// ResponseWriter wraps http.ResponseWriter and records the status code and
+// the size of the response.
+//
+// The status code is only sent with the first write to the body, so it can
+// be changed by calling WriteHeader again until then. Call WriteHeaderNow to
+// send it without a body.
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	http.Pusher
+	io.StringWriter
+
+	// Status returns the status code of the response, 200 by default.
+	Status() int
+	// Size returns the number of bytes written to the body.
+	Size() int
+	// Written reports whether the header was sent.
+	Written() bool
+	// WriteHeaderNow sends the header if it was not sent yet.
+	WriteHeaderNow()
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	size    int
+	written bool
+}
+
+var _ ResponseWriter = (*responseWriter)(nil)
+
+func (w *responseWriter) reset(writer http.ResponseWriter) {
+	w.ResponseWriter = writer
+	w.status = http.StatusOK
+	w.size = 0
+	w.written = false
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	// informational responses are sent immediately and do not end the header.
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *responseWriter) WriteHeaderNow() {
+	if !w.written {
+		w.written = true
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *responseWriter) Write(data []byte) (n int, err error) {
+	w.WriteHeaderNow()
+	n, err = w.ResponseWriter.Write(data)
+	w.size += n
+	return
+}
+
+func (w *responseWriter) WriteString(s string) (n int, err error) {
+	w.WriteHeaderNow()
+	n, err = io.WriteString(w.ResponseWriter, s)
+	w.size += n
+	return
+}
+
+func (w *responseWriter) Status() int { return w.status }
+
+func (w *responseWriter) Size() int { return w.size }
+
+func (w *responseWriter) Written() bool { return w.written }
+
+// Flush sends the header and the buffered data to the client, it does
+// nothing else if the underlying writer is not a http.Flusher.
+func (w *responseWriter) Flush() {
+	w.WriteHeaderNow()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets the caller take over the connection. The response is marked
+// as written with the status 101 Switching Protocols, so that the
+// middlewares report it, but the status code is not sent.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("gweb: the response writer does not implement http.Hijacker")
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.status = http.StatusSwitchingProtocols
+		w.written = true
+	}
+	return conn, rw, err
+}
+
+// Push initiates an HTTP/2 server push, it returns http.ErrNotSupported if
+// the underlying writer is not a http.Pusher.
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the underlying writer, it is used by http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,108 @@
+package gweb
+
+import (
+	"bufio"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseWriterStatus(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := &responseWriter{}
+	w.reset(rec)
+
+	assert.Equal(t, http.StatusOK, w.Status())
+	assert.Equal(t, 0, w.Size())
+	assert.False(t, w.Written())
+
+	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(http.StatusAccepted) // overrides, not sent yet
+	assert.Equal(t, http.StatusAccepted, w.Status())
+	assert.False(t, w.Written())
+
+	n, err := w.Write([]byte("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+	n, err = w.WriteString(" world")
+	assert.NoError(t, err)
+	assert.Equal(t, 6, n)
+
+	w.WriteHeader(http.StatusNotFound) // ignored once written
+	assert.True(t, w.Written())
+	assert.Equal(t, http.StatusAccepted, w.Status())
+	assert.Equal(t, 11, w.Size())
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "hello world", rec.Body.String())
+}
+
+func TestResponseWriterFlush(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := &responseWriter{}
+	w.reset(rec)
+
+	w.WriteHeader(http.StatusNoContent)
+	w.Flush()
+	assert.True(t, w.Written())
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+func TestResponseWriterUnsupported(t *testing.T) {
+	w := &responseWriter{}
+	w.reset(httptest.NewRecorder())
+
+	_, _, err := w.Hijack()
+	assert.Error(t, err)
+	assert.False(t, w.Written())
+	assert.Equal(t, http.StatusOK, w.Status())
+	assert.Equal(t, http.ErrNotSupported, w.Push("/style.css", nil))
+}
+
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (h *hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	return nil, nil, nil
+}
+
+func TestResponseWriterHijack(t *testing.T) {
+	rec := &hijackableRecorder{ResponseRecorder: httptest.NewRecorder()}
+	w := &responseWriter{}
+	w.reset(rec)
+
+	_, _, err := w.Hijack()
+	assert.NoError(t, err)
+	assert.True(t, rec.hijacked)
+	assert.True(t, w.Written())
+	assert.Equal(t, http.StatusSwitchingProtocols, w.Status())
+	assert.Equal(t, rec, http.ResponseWriter(w.Unwrap()))
+}
+
+func TestContextStatusTwice(t *testing.T) {
+	s := NewServer()
+	var status, size int
+	s.GET("/", func(c *Context) {
+		c.Next()
+		status, size = c.Writer().Status(), c.Writer().Size()
+	}, func(c *Context) {
+		c.Status(http.StatusCreated)
+		c.String(http.StatusAccepted, "ok")
+	})
+	s.GET("/empty", func(c *Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	w := performRequest(s, MethodGet, "/")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, http.StatusAccepted, status)
+	assert.Equal(t, 2, size)
+
+	w = performRequest(s, MethodGet, "/empty")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
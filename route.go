@@ -0,0 +1,208 @@
+package gweb
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Route is a route registered with RouterGroup.Handle.
+type Route struct {
+	s        *Server
+	Method   string
+	Host     string // host pattern of the group, empty for any host
+	Path     string // full path, including the base path of the group
+	Handlers Handlers
+	Source   string // file:line of the registration
+	name     string
+}
+
+// Name names the route so that its URL can be built with Server.URL.
+// Names are unique per server. Naming a route which is not registered, e.g.
+// rejected with DeferRouteErrors or removed, does nothing.
+func (r *Route) Name(name string) *Route {
+	Assert(name != "", "route name can not be empty")
+	r.s.routesMu.Lock()
+	defer r.s.routesMu.Unlock()
+
+	if !r.s.registered(r) {
+		return r
+	}
+	if old, exist := r.s.namedRoutes[name]; exist && old != r {
+		panic(fmt.Sprintf("route name '%s' is already used by '%s %s'", name, old.Method, old.Path))
+	}
+	if r.name != "" {
+		delete(r.s.namedRoutes, r.name)
+	}
+	r.name = name
+	r.s.namedRoutes[name] = r
+	return r
+}
+
+// registered reports whether r is one of the routes of s.
+// s.routesMu must be held.
+func (s *Server) registered(r *Route) bool {
+	for _, old := range s.routes {
+		if old == r {
+			return true
+		}
+	}
+	return false
+}
+
+// URL builds the path of the route named name. params are key-value pairs
+// filling the :param and *catchall segments of the route, e.g.
+//
+//	s.GET("/hello/:name", hello).Name("hello")
+//	s.URL("hello", "name", "gweb") // "/hello/gweb"
+//
+// Parameter values are escaped, the slashes of catch-all values are kept.
+func (s *Server) URL(name string, params ...interface{}) (string, error) {
+	s.routesMu.Lock()
+	r, exist := s.namedRoutes[name]
+	s.routesMu.Unlock()
+	if !exist {
+		return "", fmt.Errorf("gweb: no route named '%s'", name)
+	}
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("gweb: odd number of parameters for route '%s'", name)
+	}
+
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		key, ok := params[i].(string)
+		if !ok {
+			return "", fmt.Errorf("gweb: parameter name %v of route '%s' is not a string", params[i], name)
+		}
+		values[key] = fmt.Sprint(params[i+1])
+	}
+	return buildPath(r.Path, values)
+}
+
+// buildPath replaces the wildcards of the route path with values.
+func buildPath(fullPath string, values map[string]string) (string, error) {
+	rp := parseRoutePath(fullPath)
+	path := rp.clean
+	var buf strings.Builder
+	used := make(map[string]bool, len(values))
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c != ':' && c != '*' {
+			buf.WriteByte(c)
+			continue
+		}
+
+		end := i + 1
+		for end < len(path) && path[end] != '/' {
+			end++
+		}
+		key := path[i+1 : end]
+		val, exist := values[key]
+		if !exist && rp.isOptional(i-1) && len(used) == len(values) {
+			// leave out the optional segments, along with their '/'.
+			built := strings.TrimSuffix(buf.String(), "/")
+			if built == "" {
+				built = "/"
+			}
+			return built, nil
+		}
+		if !exist {
+			return "", fmt.Errorf("gweb: missing parameter '%s' for path '%s'", key, fullPath)
+		}
+		used[key] = true
+		if constraint := rp.constraints[i]; constraint != nil && !constraint.match(val) {
+			return "", fmt.Errorf("gweb: value '%s' of parameter '%s' does not match %s in path '%s'", val, key, constraint, fullPath)
+		}
+
+		if c == ':' {
+			buf.WriteString(url.PathEscape(val))
+		} else {
+			// the catch-all value starts after the '/' already written.
+			segments := strings.Split(strings.TrimPrefix(val, "/"), "/")
+			for j := range segments {
+				segments[j] = url.PathEscape(segments[j])
+			}
+			buf.WriteString(strings.Join(segments, "/"))
+		}
+		i = end - 1
+	}
+
+	for key := range values {
+		if !used[key] {
+			return "", fmt.Errorf("gweb: unknown parameter '%s' for path '%s'", key, fullPath)
+		}
+	}
+	return buf.String(), nil
+}
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	Method string
+	Host   string
+	Path   string
+	Name   string
+	// Handlers are the names of the functions handling the route, the
+	// middlewares first and the route handler last.
+	Handlers    []string
+	Middlewares int
+}
+
+// Handler returns the name of the route handler.
+func (ri RouteInfo) Handler() string {
+	if len(ri.Handlers) == 0 {
+		return ""
+	}
+	return ri.Handlers[len(ri.Handlers)-1]
+}
+
+// Routes returns the registered routes sorted by host, path and method.
+func (s *Server) Routes() []RouteInfo {
+	s.routesMu.Lock()
+	defer s.routesMu.Unlock()
+
+	infos := make([]RouteInfo, 0, len(s.routes))
+	for _, r := range s.routes {
+		names := make([]string, len(r.Handlers))
+		for i, h := range r.Handlers {
+			names[i] = nameOfFunction(h)
+		}
+		infos = append(infos, RouteInfo{
+			Method:      r.Method,
+			Host:        r.Host,
+			Path:        r.Path,
+			Name:        r.name,
+			Handlers:    names,
+			Middlewares: len(r.Handlers) - 1,
+		})
+	}
+	sort.SliceStable(infos, func(i, j int) bool {
+		if infos[i].Host != infos[j].Host {
+			return infos[i].Host < infos[j].Host
+		}
+		if infos[i].Path != infos[j].Path {
+			return infos[i].Path < infos[j].Path
+		}
+		return infos[i].Method < infos[j].Method
+	})
+	return infos
+}
+
+func (s *Server) printRoutes(w io.Writer) {
+	if !s.PrintRoutes {
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, ri := range s.Routes() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t(%d middlewares)\n", ri.Method, ri.Host+ri.Path, ri.Handler(), ri.Middlewares)
+	}
+	tw.Flush()
+}
+
+func nameOfFunction(f interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
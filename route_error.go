@@ -0,0 +1,110 @@
+package gweb
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// RouteError reports a route which can not be registered.
+type RouteError struct {
+	Method string
+	Host   string // host pattern of the rejected route, if any
+	Path   string // full path of the rejected route
+	Source string // file:line of the registration
+	Reason string
+	// Conflict is the registered route the path conflicts with, nil if the
+	// path is invalid on its own.
+	Conflict *Route
+}
+
+func (e *RouteError) Error() string {
+	msg := fmt.Sprintf("gweb: can not register %s %s%s (%s): %s", e.Method, e.Host, e.Path, e.Source, e.Reason)
+	if e.Conflict != nil {
+		msg += fmt.Sprintf("; conflicts with %s %s%s (%s)", e.Conflict.Method, e.Conflict.Host, e.Conflict.Path, e.Conflict.Source)
+	}
+	return msg
+}
+
+// RouteErrors are the errors collected while registering routes with
+// DeferRouteErrorsOption.
+type RouteErrors []*RouteError
+
+func (errs RouteErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Validate returns the RouteErrors of the routes which could not be
+// registered since DeferRouteErrors was enabled, nil if there is none.
+// Serve refuses to start while Validate fails.
+func (s *Server) Validate() error {
+	s.routesMu.Lock()
+	defer s.routesMu.Unlock()
+
+	if len(s.routeErrors) == 0 {
+		return nil
+	}
+	return append(RouteErrors(nil), s.routeErrors...)
+}
+
+// routeError returns the error for r rejected by the tree for reason, naming
+// the registered route the path conflicts with, if any.
+// s.routesMu must be held.
+func (s *Server) routeError(r *Route, reason string) *RouteError {
+	err := &RouteError{Method: r.Method, Host: r.Host, Path: r.Path, Source: r.Source, Reason: reason}
+	if tryAdd(NewRouter(), r.Path, r.Handlers) != "" {
+		return err
+	}
+	// the path conflicts with the registered routes, find the first one
+	// which can not share a tree with it.
+	for _, old := range s.routes {
+		if old.Host != r.Host || old.Method != r.Method {
+			continue
+		}
+		t := NewRouter()
+		t.Add(old.Path, old.Handlers)
+		if tryAdd(t, r.Path, r.Handlers) != "" {
+			err.Conflict = old
+			break
+		}
+	}
+	return err
+}
+
+// tryAdd adds path to router and returns the reason of the panic of the
+// tree, if any.
+func tryAdd(router Router, path string, handlers Handlers) (reason string) {
+	defer func() {
+		if v := recover(); v != nil {
+			reason = fmt.Sprint(v)
+		}
+	}()
+	router.Add(path, handlers)
+	return ""
+}
+
+// gwebPackage is the prefix of the functions of this package.
+var gwebPackage = func() string {
+	name := nameOfFunction(Assert)
+	return name[:strings.LastIndex(name, ".")+1]
+}()
+
+// callerSource returns the file:line of the first caller outside of this
+// package, the tests of the package excepted.
+func callerSource() string {
+	pc := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pc[:runtime.Callers(2, pc)])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, gwebPackage) || strings.HasSuffix(frame.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package gweb
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"strings"
+	"testing"
+)
+
+// panicReason returns the reason of the *RouteError fn panics with.
+func panicReason(fn func()) (reason string) {
+	defer func() {
+		if err, ok := recover().(*RouteError); ok {
+			reason = err.Reason
+		}
+	}()
+	fn()
+	return ""
+}
+
+func TestTryHandle(t *testing.T) {
+	s := NewServer()
+	users := s.GET("/users/:id", emptyHandler)
+	s.GET("/users/:id/posts", emptyHandler)
+	s.POST("/users/:name", emptyHandler)
+
+	r, err := s.TryHandle(MethodGet, "/users/:name/friends", emptyHandler)
+	assert.Nil(t, r)
+	var re *RouteError
+	assert.True(t, errors.As(err, &re))
+	assert.Equal(t, MethodGet, re.Method)
+	assert.Equal(t, "/users/:name/friends", re.Path)
+	assert.True(t, strings.HasSuffix(re.Source, "route_error_test.go:28"), re.Source)
+	assert.Same(t, users, re.Conflict)
+	assert.True(t, strings.HasSuffix(users.Source, "route_error_test.go:24"), users.Source)
+	assert.Contains(t, err.Error(), "gweb: can not register GET /users/:name/friends (")
+	assert.Contains(t, err.Error(), "; conflicts with GET /users/:id (")
+
+	// the tree is left unchanged.
+	assert.Equal(t, 3, len(s.Routes()))
+	assert.Equal(t, 200, performRequest(s, MethodGet, "/users/1/posts").Code)
+	assert.Equal(t, 404, performRequest(s, MethodGet, "/users/1/friends").Code)
+
+	// invalid on its own.
+	_, err = s.Group("/api").TryHandle(MethodGet, "/a/:id<int", emptyHandler)
+	assert.True(t, errors.As(err, &re))
+	assert.Equal(t, "/api/a/:id<int", re.Path)
+	assert.Nil(t, re.Conflict)
+	assert.Equal(t, "unterminated constraint in path '/api/a/:id<int'", re.Reason)
+
+	for _, tt := range []struct {
+		method, path string
+		handlers     []Handler
+		reason       string
+	}{
+		{MethodGet, "a", []Handler{emptyHandler}, "path must begin with '/' in path 'a'"},
+		{MethodGet, "", []Handler{emptyHandler}, "path must begin with '/' in path ''"},
+		{"", "/a", []Handler{emptyHandler}, "HTTP method can not be empty in path '/a'"},
+		{"get", "/a", []Handler{emptyHandler}, "http method 'get' is not valid"},
+		{MethodGet, "/a", nil, "there must be at least one handler"},
+	} {
+		_, err := s.TryHandle(tt.method, tt.path, tt.handlers...)
+		assert.True(t, errors.As(err, &re))
+		assert.Equal(t, tt.reason, re.Reason)
+	}
+
+	r, err = s.TryHandle(MethodGet, "/users/:id/friends", emptyHandler)
+	assert.NoError(t, err)
+	assert.Equal(t, "/users/:id/friends", r.Path)
+}
+
+func TestDeferRouteErrors(t *testing.T) {
+	s := NewServer()
+	DeferRouteErrorsOption(true)(s)
+	s.GET("/files/*path", emptyHandler)
+	s.GET("/files/:name", emptyHandler)
+	s.GET("/users/:id", emptyHandler)
+	s.GET("/users/:id", emptyHandler).Name("dup")
+	s.GET("/ok", emptyHandler)
+
+	err := s.Validate()
+	var errs RouteErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "/files/:name", errs[0].Path)
+	assert.Equal(t, "/files/*path", errs[0].Conflict.Path)
+	assert.Equal(t, "a handle is already registered for path '/users/:id'", errs[1].Reason)
+	assert.Equal(t, 2, strings.Count(err.Error(), "\n")+1)
+	assert.Equal(t, 200, performRequest(s, MethodGet, "/ok").Code)
+	// the rejected route is not named.
+	_, urlErr := s.URL("dup")
+	assert.EqualError(t, urlErr, "gweb: no route named 'dup'")
+
+	l, lerr := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, lerr)
+	assert.Equal(t, err, s.Serve(l))
+
+	assert.NoError(t, NewServer().Validate())
+}
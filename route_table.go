@@ -0,0 +1,253 @@
+package gweb
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// The route table is copy-on-write: requests are routed with the table
+// loaded from Server.table without locking. A route change is made in place
+// on a pending tree of its host and method, which only the writers see. The
+// pending trees are published at once by swapping the whole table when the
+// table is next loaded, so that registering n routes before serving costs
+// a single publication instead of n copies of the tree.
+
+// routeTable holds the trees by method of the default host and of the
+// hosts registered with Server.Host.
+type routeTable struct {
+	trees map[string]Router
+	hosts []*hostTrees // the hosts without params first
+}
+
+// hostTrees are the trees by method of a host pattern.
+type hostTrees struct {
+	host  *hostPattern
+	trees map[string]Router
+}
+
+// pendingTree is a tree changed since the table was published, nil if its
+// method has no route left.
+type pendingTree struct {
+	host, method string
+	tree         Router
+}
+
+// loadTable publishes the pending trees, if any, and returns the current
+// route table. It must not be modified.
+func (s *Server) loadTable() *routeTable {
+	if atomic.LoadInt32(&s.tablePending) != 0 {
+		s.routesMu.Lock()
+		s.publish()
+		s.routesMu.Unlock()
+	}
+	return s.publishedTable()
+}
+
+// publishedTable returns the route table without publishing the pending
+// trees.
+func (s *Server) publishedTable() *routeTable {
+	if t, ok := s.table.Load().(*routeTable); ok {
+		return t
+	}
+	return &routeTable{}
+}
+
+// loadTrees returns the current trees of the default host by method.
+func (s *Server) loadTrees() map[string]Router {
+	return s.loadTable().trees
+}
+
+// match returns the trees of the first host pattern matching host with its
+// params, or the trees of the default host.
+func (t *routeTable) match(host string) (map[string]Router, Params) {
+	if len(t.hosts) == 0 {
+		return t.trees, nil
+	}
+	labels := splitHost(host)
+	for _, h := range t.hosts {
+		if params, ok := h.host.match(labels); ok {
+			return h.trees, params
+		}
+	}
+	return t.trees, nil
+}
+
+// find returns the trees of the host pattern.
+func (t *routeTable) find(host string) map[string]Router {
+	if host == "" {
+		return t.trees
+	}
+	for _, h := range t.hosts {
+		if h.host.pattern == host {
+			return h.trees
+		}
+	}
+	return nil
+}
+
+// storeTree replaces the tree of host and method, a nil tree removes the
+// method. s.routesMu must be held.
+func (s *Server) storeTree(host, method string, tree Router) {
+	old := s.publishedTable()
+	t := &routeTable{trees: old.trees}
+	if host == "" {
+		t.hosts = old.hosts
+		t.trees = replaceTree(old.trees, method, tree)
+		s.table.Store(t)
+		return
+	}
+
+	found := false
+	for _, h := range old.hosts {
+		if h.host.pattern == host {
+			found = true
+			h = &hostTrees{host: h.host, trees: replaceTree(h.trees, method, tree)}
+		}
+		if len(h.trees) > 0 {
+			t.hosts = append(t.hosts, h)
+		}
+	}
+	if !found && tree != nil {
+		h := &hostTrees{host: parseHostPattern(host), trees: map[string]Router{method: tree}}
+		// keep the hosts without params before the others.
+		i := len(t.hosts)
+		if h.host.params == 0 {
+			i = sort.Search(len(t.hosts), func(j int) bool { return t.hosts[j].host.params > 0 })
+		}
+		t.hosts = append(t.hosts, nil)
+		copy(t.hosts[i+1:], t.hosts[i:])
+		t.hosts[i] = h
+	}
+	s.table.Store(t)
+}
+
+// replaceTree returns a copy of trees with the tree of method replaced.
+func replaceTree(trees map[string]Router, method string, tree Router) map[string]Router {
+	replaced := make(map[string]Router, len(trees)+1)
+	for m, t := range trees {
+		replaced[m] = t
+	}
+	if tree == nil {
+		delete(replaced, method)
+	} else {
+		replaced[method] = tree
+	}
+	return replaced
+}
+
+// buildTree returns a tree holding the registered routes of host and
+// method, nil if there is none. s.routesMu must be held.
+func (s *Server) buildTree(host, method string) Router {
+	var router Router
+	for _, r := range s.routes {
+		if r.Host != host || r.Method != method {
+			continue
+		}
+		if router == nil {
+			router = NewRouter()
+		}
+		router.Add(r.Path, r.Handlers)
+	}
+	return router
+}
+
+// copyTree returns a copy of the published tree of host and method which
+// can be modified. s.routesMu must be held.
+func (s *Server) copyTree(host, method string) Router {
+	if n, ok := s.publishedTable().find(host)[method].(*Node); ok {
+		return n.clone()
+	}
+	if router := s.buildTree(host, method); router != nil {
+		return router
+	}
+	return NewRouter()
+}
+
+// pendingTree returns the pending tree of host and method, copying the
+// published one if there is none yet. s.routesMu must be held.
+func (s *Server) pendingTree(host, method string) *pendingTree {
+	key := host + " " + method
+	if p, ok := s.pending[key]; ok {
+		if p.tree == nil {
+			p.tree = NewRouter()
+		}
+		return p
+	}
+	p := &pendingTree{host: host, method: method, tree: s.copyTree(host, method)}
+	if s.pending == nil {
+		s.pending = make(map[string]*pendingTree)
+	}
+	s.pending[key] = p
+	return p
+}
+
+// setPending replaces the pending tree of host and method, to be published
+// when the table is next loaded. s.routesMu must be held.
+func (s *Server) setPending(host, method string, tree Router) {
+	if s.pending == nil {
+		s.pending = make(map[string]*pendingTree)
+	}
+	s.pending[host+" "+method] = &pendingTree{host: host, method: method, tree: tree}
+	atomic.StoreInt32(&s.tablePending, 1)
+}
+
+// publish stores the pending trees in the table. s.routesMu must be held.
+func (s *Server) publish() {
+	for key, p := range s.pending {
+		s.storeTree(p.host, p.method, p.tree)
+		delete(s.pending, key)
+	}
+	atomic.StoreInt32(&s.tablePending, 0)
+}
+
+// addRoute registers r, or returns why it can not be registered. The
+// current trees are left unchanged in this case.
+func (s *Server) addRoute(r *Route) *RouteError {
+	s.routesMu.Lock()
+	defer s.routesMu.Unlock()
+
+	_, wasPending := s.pending[r.Host+" "+r.Method]
+	p := s.pendingTree(r.Host, r.Method)
+	if reason := tryAdd(p.tree, r.Path, r.Handlers); reason != "" {
+		// the tree may be left half changed by the panic.
+		if wasPending {
+			p.tree = s.buildTree(r.Host, r.Method)
+		} else {
+			delete(s.pending, r.Host+" "+r.Method)
+		}
+		return s.routeError(r, reason)
+	}
+	s.routes = append(s.routes, r)
+	atomic.StoreInt32(&s.tablePending, 1)
+	return nil
+}
+
+// RemoveRoute unregisters the route of method and path, path being the full
+// path as registered, e.g. "/users/:id<int>". It reports whether the route
+// was found. Routes can be added and removed while the server is running,
+// the requests already routed keep their handlers.
+func (s *Server) RemoveRoute(method, path string) bool {
+	return s.RemoveHostRoute("", method, path)
+}
+
+// RemoveHostRoute is like RemoveRoute for the routes registered on the
+// group returned by Host(host).
+func (s *Server) RemoveHostRoute(host, method, path string) bool {
+	host = normalizeHost(host)
+
+	s.routesMu.Lock()
+	defer s.routesMu.Unlock()
+
+	for i, r := range s.routes {
+		if r.Host != host || r.Method != method || r.Path != path {
+			continue
+		}
+		s.routes = append(s.routes[:i:i], s.routes[i+1:]...)
+		if r.name != "" && s.namedRoutes[r.name] == r {
+			delete(s.namedRoutes, r.name)
+		}
+		s.setPending(host, method, s.buildTree(host, method))
+		return true
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package gweb
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestRemoveRoute(t *testing.T) {
+	s := NewServer()
+	s.GET("/users/:id<int>", paramsHandler("id"))
+	s.GET("/users/:name", paramsHandler("name")).Name("user")
+	s.POST("/users/:name", emptyHandler)
+
+	assert.True(t, s.RemoveRoute(MethodGet, "/users/:name"))
+	assert.False(t, s.RemoveRoute(MethodGet, "/users/:name"))
+	assert.False(t, s.RemoveRoute(MethodPut, "/users/:id<int>"))
+	assert.Len(t, s.Routes(), 2)
+
+	assert.Equal(t, "id [{id 1}]", performRequest(s, MethodGet, "/users/1").Body.String())
+	w := performRequest(s, MethodGet, "/users/bob")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "POST, OPTIONS", w.Header().Get("Allow"))
+	_, err := s.URL("user", "name", "bob")
+	assert.EqualError(t, err, "gweb: no route named 'user'")
+
+	// the path is free again.
+	s.GET("/users/:login", paramsHandler("login"))
+	assert.Equal(t, "login [{login bob}]", performRequest(s, MethodGet, "/users/bob").Body.String())
+
+	assert.True(t, s.RemoveRoute(MethodPost, "/users/:name"))
+	_, exist := s.loadTrees()[MethodPost]
+	assert.False(t, exist)
+	w = performRequest(s, MethodPost, "/users/bob")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "GET, OPTIONS", w.Header().Get("Allow"))
+}
+
+func TestFailedRouteKeepsTree(t *testing.T) {
+	s := NewServer()
+	s.GET("/a/:id", emptyHandler)
+	tree := s.loadTrees()[MethodGet]
+
+	_, err := s.TryHandle(MethodGet, "/a/:name/b", emptyHandler)
+	assert.Error(t, err)
+	assert.True(t, tree == s.loadTrees()[MethodGet])
+
+	// the routes pending publication are kept when a route fails.
+	s.GET("/b", emptyHandler)
+	_, err = s.TryHandle(MethodGet, "/a/:name/c", emptyHandler)
+	assert.Error(t, err)
+	s.GET("/c", emptyHandler)
+	for _, path := range []string{"/a/1", "/b", "/c"} {
+		assert.Equal(t, http.StatusOK, performRequest(s, MethodGet, path).Code, path)
+	}
+}
+
+func TestConcurrentRouteChanges(t *testing.T) {
+	s := NewServer()
+	s.GET("/static/:id", emptyHandler)
+
+	const n = 50
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				w := performRequest(s, MethodGet, "/static/1")
+				if w.Code != http.StatusOK {
+					t.Errorf("GET /static/1: %d", w.Code)
+					return
+				}
+				code := performRequest(s, MethodGet, fmt.Sprintf("/plugins/%d/x", i%n)).Code
+				if code != http.StatusOK && code != http.StatusNotFound {
+					t.Errorf("GET /plugins/%d/x: %d", i%n, code)
+					return
+				}
+				s.Routes()
+			}
+		}(i)
+	}
+
+	for round := 0; round < 20; round++ {
+		for i := 0; i < n; i++ {
+			s.GET(fmt.Sprintf("/plugins/%d/:name", i), emptyHandler).Name(fmt.Sprintf("plugin%d", i))
+		}
+		for i := 0; i < n; i++ {
+			_, err := s.URL(fmt.Sprintf("plugin%d", i), "name", "x")
+			assert.NoError(t, err)
+			assert.True(t, s.RemoveRoute(MethodGet, fmt.Sprintf("/plugins/%d/:name", i)))
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	assert.Len(t, s.Routes(), 1)
+	assert.Equal(t, http.StatusNotFound, performRequest(s, MethodGet, "/plugins/1/x").Code)
+}
+
+func BenchmarkRegisterRoutes(b *testing.B) {
+	paths := make([]string, 5000)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("/api/v%d/users/%d/posts/:id", i%7, i)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s := NewServer()
+		for _, path := range paths {
+			s.GET(path, emptyHandler)
+		}
+		s.loadTable()
+	}
+}
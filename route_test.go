@@ -0,0 +1,110 @@
+package gweb
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestServerURL(t *testing.T) {
+	s := NewServer()
+	s.GET("/hello/:name", emptyHandler).Name("hello")
+	s.Group("/v1").GET("/users/:id/files/*filepath", emptyHandler).Name("files")
+	s.GET("/static", emptyHandler).Name("static")
+
+	u, err := s.URL("hello", "name", "gweb")
+	assert.NoError(t, err)
+	assert.Equal(t, "/hello/gweb", u)
+
+	u, err = s.URL("hello", "name", "a b/c")
+	assert.NoError(t, err)
+	assert.Equal(t, "/hello/a%20b%2Fc", u)
+
+	u, err = s.URL("files", "id", 42, "filepath", "/docs/read me.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "/v1/users/42/files/docs/read%20me.txt", u)
+
+	u, err = s.URL("static")
+	assert.NoError(t, err)
+	assert.Equal(t, "/static", u)
+
+	_, err = s.URL("missing")
+	assert.EqualError(t, err, "gweb: no route named 'missing'")
+	_, err = s.URL("hello")
+	assert.EqualError(t, err, "gweb: missing parameter 'name' for path '/hello/:name'")
+	_, err = s.URL("hello", "name")
+	assert.EqualError(t, err, "gweb: odd number of parameters for route 'hello'")
+	_, err = s.URL("hello", "name", "gweb", "id", 1)
+	assert.EqualError(t, err, "gweb: unknown parameter 'id' for path '/hello/:name'")
+}
+
+func TestRouteNameConflict(t *testing.T) {
+	s := NewServer()
+	r := s.GET("/a", emptyHandler).Name("a")
+	assert.NotPanics(t, func() { r.Name("a") })
+	assert.Panics(t, func() { s.GET("/b", emptyHandler).Name("a") })
+
+	// renaming frees the old name
+	r.Name("renamed")
+	assert.NotPanics(t, func() { s.GET("/c", emptyHandler).Name("a") })
+}
+
+func TestURLTemplateFunc(t *testing.T) {
+	s := NewServer()
+	s.LoadHTMLGlob("testdata/*.tmpl")
+	s.GET("/hello/:name", func(c *Context) {
+		c.HTML(http.StatusOK, "hello.tmpl", H{"Name": c.Param("name")})
+	}).Name("hello")
+
+	w := performRequest(s, MethodGet, "/hello/gweb")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "<a href=\"/hello/gweb\">gweb</a>\n", w.Body.String())
+}
+
+func middleware(_ *Context) {}
+
+func TestServerRoutes(t *testing.T) {
+	s := NewServer()
+	v1 := s.Group("/v1", middleware)
+	v1.POST("/users", emptyHandler).Name("createUser")
+	v1.GET("/users", middleware, emptyHandler)
+	s.GET("/ping", emptyHandler)
+
+	routes := s.Routes()
+	assert.Len(t, routes, 3)
+
+	assert.Equal(t, "GET", routes[0].Method)
+	assert.Equal(t, "/ping", routes[0].Path)
+	assert.Equal(t, []string{"github.com/chen-zyc/gweb.emptyHandler"}, routes[0].Handlers)
+	assert.Equal(t, 0, routes[0].Middlewares)
+
+	assert.Equal(t, "GET", routes[1].Method)
+	assert.Equal(t, "/v1/users", routes[1].Path)
+	assert.Equal(t, 2, routes[1].Middlewares)
+	assert.Equal(t, "github.com/chen-zyc/gweb.emptyHandler", routes[1].Handler())
+
+	assert.Equal(t, "POST", routes[2].Method)
+	assert.Equal(t, "createUser", routes[2].Name)
+	assert.Equal(t, []string{
+		"github.com/chen-zyc/gweb.middleware",
+		"github.com/chen-zyc/gweb.emptyHandler",
+	}, routes[2].Handlers)
+}
+
+func TestPrintRoutes(t *testing.T) {
+	s := NewServer()
+	s.GET("/ping", emptyHandler)
+	s.Group("/v1", middleware).POST("/users", emptyHandler)
+
+	var buf bytes.Buffer
+	s.printRoutes(&buf)
+	assert.Empty(t, buf.String())
+
+	PrintRoutesOption(true)(s)
+	s.printRoutes(&buf)
+	assert.Equal(t, ""+
+		"GET   /ping      github.com/chen-zyc/gweb.emptyHandler  (0 middlewares)\n"+
+		"POST  /v1/users  github.com/chen-zyc/gweb.emptyHandler  (1 middlewares)\n",
+		buf.String())
+}
@@ -2,7 +2,7 @@ package gweb
 
 type Router interface {
 	Add(path string, handler Handlers)
-	Find(path string) (handler Handlers, params Params, tsr bool)
+	Find(path string) (handler Handlers, params Params, fullPath string, tsr bool)
 	FindCaseInsensitivePath(path string, fixTrailingSlash bool) (ciPath []byte, found bool)
 }
 
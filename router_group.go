@@ -10,6 +10,7 @@ import (
 
 type RouterGroup struct {
 	s              *Server
+	host           string // pattern given to Server.Host
 	basePath       string
 	globalHandlers []Handler
 }
@@ -25,65 +26,143 @@ func NewGroup(s *Server, basePath string, handlers ...Handler) *RouterGroup {
 func (g *RouterGroup) Group(relativePath string, handlers ...Handler) *RouterGroup {
 	absolutePath := joinPaths(g.basePath, relativePath)
 	handlers = g.combineHandlers(handlers...)
-	return NewGroup(g.s, absolutePath, handlers...)
+	group := NewGroup(g.s, absolutePath, handlers...)
+	group.host = g.host
+	return group
 }
 
 func (g *RouterGroup) Global(handlers ...Handler) {
 	g.globalHandlers = append(g.globalHandlers, handlers...)
 }
 
-func (g *RouterGroup) Handle(method, path string, handlers ...Handler) {
-	Assert(path[0] == '/', fmt.Sprintf("path must begin with '/' in path '%s'", path))
-	Assert(method != "", fmt.Sprintf("HTTP method can not be empty in path '%s'", path))
-	Assert(len(handlers) > 0, "there must be at least one handler")
-	if matched, err := regexp.MatchString("^[A-Z]+$", method); !matched || err != nil {
-		panic(fmt.Sprintf("http method '%s' is not valid", method))
+// Handle registers the handlers for the path and method. The returned Route
+// can be named to build its URL with Server.URL.
+//
+// A parameter can be followed by a constraint between angle brackets, either
+// a type (int, uint, alpha, alnum or uuid) or a regular expression matching
+// the whole segment, e.g. "/users/:id<int>" or "/posts/:slug<[a-z-]+>".
+// Params differing by their constraint can share a position: a segment is
+// matched by the constrained ones in registration order, then by the
+// unconstrained one, and a request matching none of them is not found.
+//
+// The last segments of a path can be optional params marked by a '?', e.g.
+// "/docs/:page?" matches both "/docs" and "/docs/intro". A catch-all can be
+// followed by static segments, e.g. "/files/*path/raw" matches
+// "/files/a/b/raw" with path "/a/b". The routes sharing a catch-all are
+// tried longest suffix first, then the one ending with the catch-all.
+//
+// Handle panics with a *RouteError if the route can not be registered,
+// unless Server.DeferRouteErrors is enabled: the route is then left out and
+// the error is reported by Server.Validate.
+func (g *RouterGroup) Handle(method, path string, handlers ...Handler) *Route {
+	r, err := g.handle(method, path, handlers, callerSource())
+	if err != nil {
+		if !g.s.DeferRouteErrors {
+			panic(err)
+		}
+		g.s.routesMu.Lock()
+		g.s.routeErrors = append(g.s.routeErrors, err)
+		g.s.routesMu.Unlock()
 	}
+	return r
+}
 
-	router := g.s.trees[method]
-	if router == nil {
-		router = NewRouter()
-		g.s.trees[method] = router
+// TryHandle is like Handle but returns a *RouteError instead of panicking
+// if the route can not be registered, e.g. when its path conflicts with a
+// registered one. The route is not registered in this case.
+func (g *RouterGroup) TryHandle(method, path string, handlers ...Handler) (*Route, error) {
+	r, err := g.handle(method, path, handlers, callerSource())
+	if err != nil {
+		return nil, err
 	}
+	return r, nil
+}
+
+// handle registers the route. The route is returned even if it can not be
+// registered, so that Handle can be chained with deferred errors.
+func (g *RouterGroup) handle(method, path string, handlers Handlers, source string) (*Route, *RouteError) {
 	handlers = g.combineHandlers(handlers...) // + global handlers
-	absolutePath := joinPaths(g.basePath, path)
-	router.Add(absolutePath, handlers)
+	r := &Route{s: g.s, Method: method, Host: g.host, Path: path, Handlers: handlers, Source: source}
+	fail := func(format string, args ...interface{}) (*Route, *RouteError) {
+		return r, &RouteError{Method: method, Host: g.host, Path: r.Path, Source: source, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	if path == "" || path[0] != '/' {
+		return fail("path must begin with '/' in path '%s'", path)
+	}
+	r.Path = joinPaths(g.basePath, path)
+	if method == "" {
+		return fail("HTTP method can not be empty in path '%s'", path)
+	}
+	if len(handlers) == len(g.globalHandlers) {
+		return fail("there must be at least one handler")
+	}
+	if matched, err := regexp.MatchString("^[A-Z]+$", method); !matched || err != nil {
+		return fail("http method '%s' is not valid", method)
+	}
+
+	if err := g.s.addRoute(r); err != nil {
+		return r, err
+	}
+	return r, nil
 }
 
-func (g *RouterGroup) GET(path string, handlers ...Handler) {
-	g.Handle(MethodGet, path, handlers...)
+func (g *RouterGroup) GET(path string, handlers ...Handler) *Route {
+	return g.Handle(MethodGet, path, handlers...)
 }
 
-func (g *RouterGroup) POST(path string, handlers ...Handler) {
-	g.Handle(MethodPost, path, handlers...)
+func (g *RouterGroup) POST(path string, handlers ...Handler) *Route {
+	return g.Handle(MethodPost, path, handlers...)
 }
 
-func (g *RouterGroup) PUT(path string, handlers ...Handler) {
-	g.Handle(MethodPut, path, handlers...)
+func (g *RouterGroup) PUT(path string, handlers ...Handler) *Route {
+	return g.Handle(MethodPut, path, handlers...)
 }
 
-func (g *RouterGroup) DELETE(path string, handlers ...Handler) {
-	g.Handle(MethodDelete, path, handlers...)
+func (g *RouterGroup) DELETE(path string, handlers ...Handler) *Route {
+	return g.Handle(MethodDelete, path, handlers...)
 }
 
-func (g *RouterGroup) PATCH(path string, handlers ...Handler) {
-	g.Handle(MethodPatch, path, handlers...)
+func (g *RouterGroup) PATCH(path string, handlers ...Handler) *Route {
+	return g.Handle(MethodPatch, path, handlers...)
 }
 
-func (g *RouterGroup) OPTIONS(path string, handlers ...Handler) {
-	g.Handle(MethodOptions, path, handlers...)
+func (g *RouterGroup) OPTIONS(path string, handlers ...Handler) *Route {
+	return g.Handle(MethodOptions, path, handlers...)
 }
 
-func (g *RouterGroup) HEAD(path string, handlers ...Handler) {
-	g.Handle(MethodHead, path, handlers...)
+func (g *RouterGroup) HEAD(path string, handlers ...Handler) *Route {
+	return g.Handle(MethodHead, path, handlers...)
 }
 
-func (g *RouterGroup) CONNECT(path string, handlers ...Handler) {
-	g.Handle(MethodConnect, path, handlers...)
+func (g *RouterGroup) CONNECT(path string, handlers ...Handler) *Route {
+	return g.Handle(MethodConnect, path, handlers...)
 }
 
-func (g *RouterGroup) TRACE(path string, handlers ...Handler) {
-	g.Handle(MethodTrace, path, handlers...)
+func (g *RouterGroup) TRACE(path string, handlers ...Handler) *Route {
+	return g.Handle(MethodTrace, path, handlers...)
+}
+
+// WebSocket registers a GET route which upgrades the connection to the
+// WebSocket protocol with Server.WebSocketUpgrader, then calls the handlers.
+// The connection is available from Context.WebSocket and is closed once the
+// handlers return. The global handlers run before the upgrade, so they can
+// still reject the request with a normal HTTP response.
+func (g *RouterGroup) WebSocket(path string, handlers ...Handler) *Route {
+	Assert(len(handlers) > 0, "there must be at least one handler")
+	upgrade := func(c *Context) {
+		conn, err := c.s.WebSocketUpgrader.Upgrade(c.resp, c.req, nil)
+		if err != nil {
+			// the error response is written by Upgrade, unless the
+			// connection was hijacked and closed.
+			c.Abort()
+			return
+		}
+		defer conn.Close()
+		c.ws = conn
+		c.Next()
+	}
+	return g.Handle(MethodGet, path, append(Handlers{upgrade}, handlers...)...)
 }
 
 func (g *RouterGroup) HandleMethods(methods []string, path string, handlers ...Handler) {
@@ -66,3 +66,28 @@ func performRequest(r http.Handler, method, path string) *httptest.ResponseRecor
 	r.ServeHTTP(w, req)
 	return w
 }
+
+func TestGroupGlobalHandlersAbort(t *testing.T) {
+	s := NewServer()
+	admin := s.Group("/admin", func(c *Context) {
+		if c.Query("token") != "secret" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+		}
+	})
+	users := admin.Group("/users", func(c *Context) {
+		c.Header("X-Group", "users")
+	})
+	users.GET("/list", func(c *Context) {
+		c.String(http.StatusOK, "users")
+	})
+
+	w := performRequest(s, MethodGet, "/admin/users/list")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, w.Header().Get("X-Group"))
+	assert.Empty(t, w.Body.String())
+
+	w = performRequest(s, MethodGet, "/admin/users/list?token=secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "users", w.Header().Get("X-Group"))
+	assert.Equal(t, "users", w.Body.String())
+}
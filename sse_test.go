@@ -0,0 +1,86 @@
+package gweb
+
+import (
+	"bufio"
+	"github.com/chen-zyc/gweb/render"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSSEventRender(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := render.SSEvent{Event: "update", ID: "7\n", Retry: 3000, Data: "line1\nline2"}.Render(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "id: 7\nevent: update\nretry: 3000\ndata: line1\ndata: line2\n\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	assert.NoError(t, render.SSEvent{Data: H{"n": 1}}.Render(w))
+	assert.Equal(t, "data: {\"n\":1}\n\n", w.Body.String())
+
+	// a lone "\r" can not inject a field.
+	w = httptest.NewRecorder()
+	assert.NoError(t, render.SSEvent{Data: "a\revent: forged\r\nb\n\rc"}.Render(w))
+	assert.Equal(t, "data: a\ndata: event: forged\ndata: b\ndata: \ndata: c\n\n", w.Body.String())
+}
+
+func TestContextSSEventStream(t *testing.T) {
+	s := NewServer()
+	s.GET("/events", func(c *Context) {
+		start, _ := strconv.Atoi(c.LastEventID())
+		i := start
+		closed := c.Stream(func(w io.Writer) bool {
+			i++
+			c.Render(render.SSEvent{Event: "tick", ID: strconv.Itoa(i), Data: i})
+			return i < start+3
+		})
+		assert.False(t, closed)
+	})
+
+	req := httptest.NewRequest(MethodGet, "/events", nil)
+	req.Header.Set("Last-Event-ID", "5")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	assert.True(t, w.Flushed)
+	assert.Equal(t, ""+
+		"id: 6\nevent: tick\ndata: 6\n\n"+
+		"id: 7\nevent: tick\ndata: 7\n\n"+
+		"id: 8\nevent: tick\ndata: 8\n\n", w.Body.String())
+}
+
+func TestContextStreamClientDisconnect(t *testing.T) {
+	s := NewServer()
+	result := make(chan bool, 1)
+	s.GET("/events", func(c *Context) {
+		result <- c.Stream(func(w io.Writer) bool {
+			c.SSEvent("ping", "")
+			select {
+			case <-time.After(10 * time.Millisecond):
+			case <-c.Done():
+			}
+			return true
+		})
+	})
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/events")
+	assert.NoError(t, err)
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "event: ping\n", line)
+	resp.Body.Close()
+
+	select {
+	case closed := <-result:
+		assert.True(t, closed)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stream did not stop after the client disconnected")
+	}
+}
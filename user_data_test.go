@@ -0,0 +1,109 @@
+package gweb
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+var (
+	userKey  = NewKey[string]("user")
+	countKey = NewKey[int]("count")
+)
+
+func TestTypedUserData(t *testing.T) {
+	c := &Context{}
+	c.reset(nil, nil)
+
+	_, exist := Get(c, userKey)
+	assert.False(t, exist)
+
+	Set(c, userKey, "bob")
+	Set(c, countKey, 3)
+	c.SetUserData("user", "alice")
+
+	user, exist := Get(c, userKey)
+	assert.True(t, exist)
+	assert.Equal(t, "bob", user)
+	count, _ := Get(c, countKey)
+	assert.Equal(t, 3, count)
+	data, _ := c.UserData("user")
+	assert.Equal(t, "alice", data)
+
+	// keys with the same name do not collide
+	_, exist = Get(c, NewKey[string]("user"))
+	assert.False(t, exist)
+	assert.Equal(t, "bob", c.Value(userKey))
+
+	// a nil interface value
+	errKey := NewKey[error]("err")
+	Set(c, errKey, nil)
+	err, exist := Get(c, errKey)
+	assert.True(t, exist)
+	assert.Nil(t, err)
+}
+
+func TestUserDataResetOnReuse(t *testing.T) {
+	c := &Context{}
+	c.reset(nil, nil)
+	c.SetUserData("user", "bob")
+	Set(c, countKey, 1)
+
+	c.reset(nil, nil)
+	_, exist := c.UserData("user")
+	assert.False(t, exist)
+	_, exist = Get(c, countKey)
+	assert.False(t, exist)
+}
+
+func TestUserDataDoesNotLeakThroughPool(t *testing.T) {
+	s := NewServer()
+	var created int
+	s.ctxPool.New = func() interface{} {
+		created++
+		return &Context{}
+	}
+	s.GET("/set/:n", func(c *Context) {
+		n, _ := strconv.Atoi(c.Param("n"))
+		c.SetUserData("user", "bob")
+		Set(c, countKey, n)
+	})
+	s.GET("/get", func(c *Context) {
+		_, userExist := c.UserData("user")
+		_, countExist := Get(c, countKey)
+		if userExist || countExist {
+			c.Status(http.StatusConflict)
+		}
+	})
+
+	const requests = 100
+	for i := 0; i < requests; i++ {
+		performRequest(s, MethodGet, "/set/"+strconv.Itoa(i))
+		w := performRequest(s, MethodGet, "/get")
+		assert.Equal(t, http.StatusOK, w.Code, "user data leaked into request %d", i)
+	}
+	// the contexts were reused by the pool
+	assert.True(t, created < requests)
+}
+
+func TestUserDataConcurrentReads(t *testing.T) {
+	s := NewServer()
+	s.GET("/", func(c *Context) {
+		Set(c, userKey, "bob")
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				user, _ := Get(c, userKey)
+				assert.Equal(t, "bob", user)
+				c.Value("missing")
+			}()
+		}
+		Set(c, countKey, 1)
+		wg.Wait()
+	})
+	performRequest(s, MethodGet, "/")
+}
@@ -1,6 +1,9 @@
 package gweb
 
-import "path"
+import (
+	"path"
+	"strings"
+)
 
 type H map[string]interface{}
 
@@ -24,3 +27,12 @@ func lastChar(str string) uint8 {
 	}
 	return str[size-1]
 }
+
+// filterFlags returns the content before the first ';' or ' ', e.g. the media
+// type of "text/html; charset=utf-8".
+func filterFlags(content string) string {
+	if i := strings.IndexAny(content, "; "); i >= 0 {
+		return content[:i]
+	}
+	return content
+}
@@ -0,0 +1,518 @@
+// Package websocket implements the WebSocket protocol defined in RFC 6455
+// on top of http.Hijacker, without extensions.
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Message types, as defined by the opcodes of RFC 6455, section 11.8.
+const (
+	continuationFrame = 0
+	TextMessage       = 1
+	BinaryMessage     = 2
+	CloseMessage      = 8
+	PingMessage       = 9
+	PongMessage       = 10
+)
+
+// Close codes, as defined in RFC 6455, section 11.7.
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseMandatoryExtension      = 1010
+	CloseInternalServerErr       = 1011
+	CloseTLSHandshake            = 1015
+)
+
+const (
+	finalBit = 1 << 7
+	rsvBits  = 7 << 4
+	maskBit  = 1 << 7
+
+	maxControlPayload = 125
+	// maxPreallocPayload is the largest payload allocated at once from the
+	// length of its frame, larger ones are read in a growing buffer.
+	maxPreallocPayload = 64 << 10
+	// DefaultReadLimit is the maximum size of a message read when no limit
+	// is configured.
+	DefaultReadLimit = 1 << 20 // 1MB
+)
+
+var (
+	// ErrReadLimit is returned when a message exceeds the read limit.
+	ErrReadLimit = errors.New("websocket: message exceeds the read limit")
+	// ErrCloseSent is returned when writing a message after the close frame.
+	ErrCloseSent = errors.New("websocket: close frame already sent")
+
+	errBadWriteType = errors.New("websocket: bad message type")
+)
+
+// CloseError is returned by ReadMessage when the peer closed the connection.
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Text)
+}
+
+// IsCloseError reports whether err is a *CloseError with one of the codes.
+func IsCloseError(err error, codes ...int) bool {
+	var ce *CloseError
+	if !errors.As(err, &ce) {
+		return false
+	}
+	for _, code := range codes {
+		if ce.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatCloseMessage returns the payload of a close frame.
+// CloseNoStatusReceived results in an empty payload.
+func FormatCloseMessage(code int, text string) []byte {
+	if code == CloseNoStatusReceived {
+		return []byte{}
+	}
+	buf := make([]byte, 2+len(text))
+	binary.BigEndian.PutUint16(buf, uint16(code))
+	copy(buf[2:], text)
+	return buf
+}
+
+// protocolError is a violation of the protocol by the peer, the connection
+// is closed with CloseProtocolError.
+type protocolError string
+
+func (e protocolError) Error() string { return "websocket: " + string(e) }
+
+// Conn is a WebSocket connection. A Conn supports one concurrent reader and
+// one concurrent writer: the write methods are safe to call concurrently
+// with ReadMessage and with each other.
+type Conn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	isServer    bool
+	subprotocol string
+
+	readLimit    int64
+	readErr      error
+	pingHandler  func(data string) error
+	pongHandler  func(data string) error
+	closeHandler func(code int, text string) error
+
+	writeMu      sync.Mutex
+	closeSent    bool
+	fragmentSize int
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, isServer bool) *Conn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+	c := &Conn{
+		conn:      conn,
+		br:        br,
+		isServer:  isServer,
+		readLimit: DefaultReadLimit,
+	}
+	c.pingHandler = func(data string) error {
+		err := c.WriteControl(PongMessage, []byte(data), time.Now().Add(time.Second))
+		if errors.Is(err, ErrCloseSent) {
+			return nil
+		}
+		return err
+	}
+	c.pongHandler = func(string) error { return nil }
+	c.closeHandler = func(code int, _ string) error {
+		if code == CloseNoStatusReceived {
+			code = CloseNormalClosure
+		}
+		err := c.WriteControl(CloseMessage, FormatCloseMessage(code, ""), time.Now().Add(time.Second))
+		if errors.Is(err, ErrCloseSent) {
+			return nil
+		}
+		return err
+	}
+	return c
+}
+
+// Subprotocol returns the subprotocol negotiated during the handshake.
+func (c *Conn) Subprotocol() string { return c.subprotocol }
+
+func (c *Conn) LocalAddr() net.Addr { return c.conn.LocalAddr() }
+
+func (c *Conn) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
+
+func (c *Conn) SetReadDeadline(t time.Time) error { return c.conn.SetReadDeadline(t) }
+
+func (c *Conn) SetWriteDeadline(t time.Time) error { return c.conn.SetWriteDeadline(t) }
+
+// SetReadLimit sets the maximum size of a message, fragments included.
+// A larger message closes the connection with CloseMessageTooBig and
+// ReadMessage returns ErrReadLimit. Zero or a negative limit disables it.
+func (c *Conn) SetReadLimit(limit int64) { c.readLimit = limit }
+
+// SetPingHandler sets the function called with the payload of the ping
+// frames. The default handler answers with a pong frame.
+func (c *Conn) SetPingHandler(h func(data string) error) { c.pingHandler = h }
+
+// SetPongHandler sets the function called with the payload of the pong
+// frames. The default handler does nothing.
+func (c *Conn) SetPongHandler(h func(data string) error) { c.pongHandler = h }
+
+// SetCloseHandler sets the function called when a close frame is received,
+// before ReadMessage returns the *CloseError. The default handler echoes
+// the close code to the peer.
+func (c *Conn) SetCloseHandler(h func(code int, text string) error) { c.closeHandler = h }
+
+// Close closes the connection. A close frame with CloseNormalClosure is
+// sent first if no close frame was sent yet.
+func (c *Conn) Close() error {
+	c.WriteClose(CloseNormalClosure, "")
+	return c.conn.Close()
+}
+
+// WriteClose sends a close frame with the code and text, after which no
+// other message can be written.
+func (c *Conn) WriteClose(code int, text string) error {
+	return c.WriteControl(CloseMessage, FormatCloseMessage(code, text), time.Now().Add(time.Second))
+}
+
+// ============ reading ============
+
+// ReadMessage reads the next data message, reassembling fragmented
+// messages. Control frames are passed to the ping, pong and close handlers.
+// Once an error was returned, ReadMessage keeps returning it.
+func (c *Conn) ReadMessage() (messageType int, p []byte, err error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+	messageType, p, err = c.readMessage()
+	if err != nil {
+		c.readErr = err
+		c.failRead(err)
+	}
+	return
+}
+
+// failRead sends the close frame matching a read error.
+func (c *Conn) failRead(err error) {
+	var pe protocolError
+	switch {
+	case errors.As(err, &pe):
+		c.WriteClose(CloseProtocolError, string(pe))
+	case errors.Is(err, ErrReadLimit):
+		c.WriteClose(CloseMessageTooBig, "")
+	case errors.Is(err, errInvalidUTF8):
+		c.WriteClose(CloseInvalidFramePayloadData, "")
+	}
+}
+
+var errInvalidUTF8 = errors.New("websocket: invalid UTF-8 in text message")
+
+func (c *Conn) readMessage() (int, []byte, error) {
+	messageType := 0
+	var message []byte
+	for {
+		fin, opcode, payload, err := c.readFrame(int64(len(message)))
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case PingMessage:
+			if err := c.pingHandler(string(payload)); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if err := c.pongHandler(string(payload)); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case CloseMessage:
+			code, text, err := parseClosePayload(payload)
+			if err != nil {
+				return 0, nil, err
+			}
+			if err := c.closeHandler(code, text); err != nil {
+				return 0, nil, err
+			}
+			return 0, nil, &CloseError{Code: code, Text: text}
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, protocolError("data frame inside a fragmented message")
+			}
+			messageType = opcode
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, protocolError("continuation frame without a message")
+			}
+		default:
+			return 0, nil, protocolError(fmt.Sprintf("unknown opcode %d", opcode))
+		}
+
+		message = append(message, payload...)
+		if fin {
+			if messageType == TextMessage && !utf8.Valid(message) {
+				return 0, nil, errInvalidUTF8
+			}
+			if message == nil {
+				message = []byte{}
+			}
+			return messageType, message, nil
+		}
+	}
+}
+
+// readFrame reads a single frame. buffered is the size of the fragments of
+// the current message already read, checked against the read limit.
+func (c *Conn) readFrame(buffered int64) (fin bool, opcode int, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(c.br, head[:]); err != nil {
+		return
+	}
+	fin = head[0]&finalBit != 0
+	opcode = int(head[0] & 0xf)
+	if head[0]&rsvBits != 0 {
+		err = protocolError("reserved bits set without a negotiated extension")
+		return
+	}
+	masked := head[1]&maskBit != 0
+	if masked != c.isServer {
+		if c.isServer {
+			err = protocolError("client frame is not masked")
+		} else {
+			err = protocolError("server frame is masked")
+		}
+		return
+	}
+
+	length := int64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var b [2]byte
+		if _, err = io.ReadFull(c.br, b[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err = io.ReadFull(c.br, b[:]); err != nil {
+			return
+		}
+		n := binary.BigEndian.Uint64(b[:])
+		if n>>63 != 0 {
+			err = protocolError("invalid payload length")
+			return
+		}
+		length = int64(n)
+	}
+
+	if opcode >= CloseMessage {
+		if !fin {
+			err = protocolError("fragmented control frame")
+			return
+		}
+		if length > maxControlPayload {
+			err = protocolError("control frame payload exceeds 125 bytes")
+			return
+		}
+	} else if c.readLimit > 0 && length > c.readLimit-buffered {
+		err = ErrReadLimit
+		return
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.br, key[:]); err != nil {
+			return
+		}
+	}
+
+	if length <= maxPreallocPayload {
+		payload = make([]byte, length)
+		if _, err = io.ReadFull(c.br, payload); err != nil {
+			return
+		}
+	} else {
+		// the length is sent by the peer, the buffer grows with the
+		// payload actually read in case the read limit is disabled.
+		var buf bytes.Buffer
+		_, err = io.CopyN(&buf, c.br, length)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return
+		}
+		payload = buf.Bytes()
+	}
+	if masked {
+		maskBytes(key, payload)
+	}
+	return
+}
+
+func parseClosePayload(payload []byte) (code int, text string, err error) {
+	switch {
+	case len(payload) == 0:
+		return CloseNoStatusReceived, "", nil
+	case len(payload) == 1:
+		return 0, "", protocolError("invalid close payload")
+	}
+	code = int(binary.BigEndian.Uint16(payload))
+	if !validCloseCode(code) {
+		return 0, "", protocolError(fmt.Sprintf("invalid close code %d", code))
+	}
+	if !utf8.Valid(payload[2:]) {
+		return 0, "", errInvalidUTF8
+	}
+	return code, string(payload[2:]), nil
+}
+
+// validCloseCode reports whether code may be sent in a close frame.
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1011:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+func maskBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i&3]
+	}
+}
+
+// ============ writing ============
+
+// SetWriteFragmentSize splits the messages larger than size into
+// continuation frames. Zero or a negative size disables fragmentation.
+func (c *Conn) SetWriteFragmentSize(size int) {
+	c.writeMu.Lock()
+	c.fragmentSize = size
+	c.writeMu.Unlock()
+}
+
+// WriteMessage writes a text or binary message.
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		if messageType >= CloseMessage {
+			return c.WriteControl(messageType, data, time.Time{})
+		}
+		return errBadWriteType
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+
+	opcode := messageType
+	for {
+		chunk := data
+		if c.fragmentSize > 0 && len(chunk) > c.fragmentSize {
+			chunk = chunk[:c.fragmentSize]
+		}
+		data = data[len(chunk):]
+		if err := c.writeFrame(len(data) == 0, opcode, chunk); err != nil {
+			return err
+		}
+		if len(data) == 0 {
+			return nil
+		}
+		opcode = continuationFrame
+	}
+}
+
+// WriteControl writes a close, ping or pong frame. The write fails if it is
+// not completed by the deadline, the zero time means no deadline.
+func (c *Conn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if messageType < CloseMessage || messageType > PongMessage {
+		return errBadWriteType
+	}
+	if len(data) > maxControlPayload {
+		return errors.New("websocket: control frame payload exceeds 125 bytes")
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	if messageType == CloseMessage {
+		c.closeSent = true
+	}
+
+	c.conn.SetWriteDeadline(deadline)
+	defer c.conn.SetWriteDeadline(time.Time{})
+	return c.writeFrame(true, messageType, data)
+}
+
+// writeFrame writes a frame, the caller holds writeMu.
+func (c *Conn) writeFrame(fin bool, opcode int, payload []byte) error {
+	buf := make([]byte, 0, 14+len(payload))
+
+	b0 := byte(opcode)
+	if fin {
+		b0 |= finalBit
+	}
+	buf = append(buf, b0)
+
+	var b1 byte
+	if !c.isServer {
+		b1 = maskBit
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		buf = append(buf, b1|byte(n))
+	case n <= 0xffff:
+		buf = append(buf, b1|126, byte(n>>8), byte(n))
+	default:
+		buf = append(buf, b1|127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+
+	if c.isServer {
+		buf = append(buf, payload...)
+	} else {
+		var key [4]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+		buf = append(buf, key[:]...)
+		start := len(buf)
+		buf = append(buf, payload...)
+		maskBytes(key, buf[start:])
+	}
+
+	_, err := c.conn.Write(buf)
+	return err
+}
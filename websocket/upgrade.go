@@ -0,0 +1,182 @@
+package websocket
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// keyGUID is the GUID appended to Sec-WebSocket-Key to compute
+// Sec-WebSocket-Accept, see RFC 6455, section 1.3.
+const keyGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// HandshakeError is returned by Upgrade when the request is not a valid
+// WebSocket handshake. The error response has already been written.
+type HandshakeError struct {
+	Status int
+	Reason string
+}
+
+func (e *HandshakeError) Error() string { return "websocket: " + e.Reason }
+
+// Upgrader upgrades HTTP connections to the WebSocket protocol.
+// The zero value is ready to use.
+type Upgrader struct {
+	// ReadLimit is the maximum size of a message read from the connection,
+	// DefaultReadLimit when zero, unlimited when negative.
+	ReadLimit int64
+	// WriteFragmentSize splits the messages larger than it into several
+	// frames, no fragmentation when zero.
+	WriteFragmentSize int
+	// Subprotocols lists the supported subprotocols in order of preference.
+	Subprotocols []string
+	// CheckOrigin reports whether the Origin of the request is acceptable.
+	// When nil, only requests without Origin or from the same host pass.
+	CheckOrigin func(r *http.Request) bool
+}
+
+// Upgrade completes the opening handshake and hijacks the connection.
+// responseHeader is added to the 101 response, Sec-WebSocket-Protocol
+// excepted. On failure an HTTP error is written and a *HandshakeError
+// returned.
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request, responseHeader http.Header) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		return nil, u.fail(w, http.StatusMethodNotAllowed, "request method is not GET")
+	}
+	if !headerContainsToken(r.Header, "Connection", "upgrade") {
+		return nil, u.fail(w, http.StatusBadRequest, "'upgrade' token not found in 'Connection' header")
+	}
+	if !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		return nil, u.fail(w, http.StatusBadRequest, "'websocket' token not found in 'Upgrade' header")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, u.fail(w, http.StatusUpgradeRequired, "unsupported version in 'Sec-WebSocket-Version' header")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, u.fail(w, http.StatusBadRequest, "invalid 'Sec-WebSocket-Key' header")
+	}
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, u.fail(w, http.StatusForbidden, "origin not allowed")
+	}
+
+	h, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, u.fail(w, http.StatusInternalServerError, "response does not implement http.Hijacker")
+	}
+	netConn, brw, err := h.Hijack()
+	if err != nil {
+		return nil, u.fail(w, http.StatusInternalServerError, "hijack failed: "+err.Error())
+	}
+	if brw.Reader.Buffered() > 0 {
+		netConn.Close()
+		return nil, errors.New("websocket: client sent data before the handshake completed")
+	}
+
+	c := newConn(netConn, brw.Reader, true)
+	c.subprotocol = u.selectSubprotocol(r)
+	switch {
+	case u.ReadLimit > 0:
+		c.readLimit = u.ReadLimit
+	case u.ReadLimit < 0:
+		c.readLimit = 0
+	}
+	c.fragmentSize = u.WriteFragmentSize
+
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: ")
+	b.WriteString(acceptKey(key))
+	b.WriteString("\r\n")
+	if c.subprotocol != "" {
+		b.WriteString("Sec-WebSocket-Protocol: " + c.subprotocol + "\r\n")
+	}
+	for k, vs := range responseHeader {
+		if http.CanonicalHeaderKey(k) == "Sec-Websocket-Protocol" {
+			continue
+		}
+		for _, v := range vs {
+			b.WriteString(k + ": " + v + "\r\n")
+		}
+	}
+	b.WriteString("\r\n")
+	if _, err := netConn.Write([]byte(b.String())); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (u *Upgrader) fail(w http.ResponseWriter, status int, reason string) error {
+	http.Error(w, http.StatusText(status), status)
+	return &HandshakeError{Status: status, Reason: reason}
+}
+
+func (u *Upgrader) selectSubprotocol(r *http.Request) string {
+	requested := Subprotocols(r)
+	for _, p := range u.Subprotocols {
+		for _, q := range requested {
+			if p == q {
+				return p
+			}
+		}
+	}
+	return ""
+}
+
+// IsWebSocketUpgrade reports whether r asks for a WebSocket upgrade.
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return headerContainsToken(r.Header, "Connection", "upgrade") &&
+		headerContainsToken(r.Header, "Upgrade", "websocket")
+}
+
+// Subprotocols returns the subprotocols requested by the client.
+func Subprotocols(r *http.Request) []string {
+	var protocols []string
+	for _, v := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				protocols = append(protocols, p)
+			}
+		}
+	}
+	return protocols
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + keyGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerContainsToken reports whether the comma separated header contains
+// token, compared case-insensitively.
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, v := range header.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
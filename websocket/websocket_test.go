@@ -0,0 +1,290 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoServer starts a server echoing every message back with u.
+func echoServer(t *testing.T, u *Upgrader) string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := u.Upgrade(w, r, http.Header{"X-Echo": {"1"}})
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			mt, p, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(mt, p); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func TestEcho(t *testing.T) {
+	url := echoServer(t, &Upgrader{Subprotocols: []string{"v2", "v1"}})
+	conn, resp, err := Dial(url, http.Header{"Sec-WebSocket-Protocol": {"v1, v2"}})
+	assert.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, "1", resp.Header.Get("X-Echo"))
+	assert.Equal(t, "v2", conn.Subprotocol())
+
+	assert.NoError(t, conn.WriteMessage(TextMessage, []byte("hello")))
+	mt, p, err := conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, TextMessage, mt)
+	assert.Equal(t, "hello", string(p))
+
+	big := bytes.Repeat([]byte{0xff}, 70000) // 64-bit length
+	assert.NoError(t, conn.WriteMessage(BinaryMessage, big))
+	mt, p, err = conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, BinaryMessage, mt)
+	assert.Equal(t, big, p)
+
+	assert.NoError(t, conn.WriteMessage(TextMessage, nil))
+	_, p, err = conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{}, p)
+}
+
+func TestFragmentation(t *testing.T) {
+	url := echoServer(t, &Upgrader{WriteFragmentSize: 3})
+	conn, _, err := Dial(url, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+	conn.SetWriteFragmentSize(2)
+
+	// the client and the server both split the message into frames.
+	assert.NoError(t, conn.WriteMessage(TextMessage, []byte("fragmented")))
+	mt, p, err := conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, TextMessage, mt)
+	assert.Equal(t, "fragmented", string(p))
+}
+
+func TestPingPong(t *testing.T) {
+	url := echoServer(t, &Upgrader{})
+	conn, _, err := Dial(url, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	pongs := make(chan string, 1)
+	conn.SetPongHandler(func(data string) error {
+		pongs <- data
+		return nil
+	})
+	assert.NoError(t, conn.WriteControl(PingMessage, []byte("ping"), time.Now().Add(time.Second)))
+	// the pong is handled while waiting for the next data message.
+	assert.NoError(t, conn.WriteMessage(TextMessage, []byte("after")))
+	_, p, err := conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, "after", string(p))
+	assert.Equal(t, "ping", <-pongs)
+}
+
+func TestReadLimit(t *testing.T) {
+	url := echoServer(t, &Upgrader{ReadLimit: 8})
+	conn, _, err := Dial(url, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+	conn.SetWriteFragmentSize(4)
+
+	assert.NoError(t, conn.WriteMessage(TextMessage, []byte("12345678")))
+	_, p, err := conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, "12345678", string(p))
+
+	// the limit applies to the whole message, not to each fragment.
+	assert.NoError(t, conn.WriteMessage(TextMessage, []byte("123456789")))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, IsCloseError(err, CloseMessageTooBig), "%v", err)
+}
+
+func TestCloseHandshake(t *testing.T) {
+	url := echoServer(t, &Upgrader{})
+	conn, _, err := Dial(url, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	assert.NoError(t, conn.WriteClose(CloseGoingAway, "bye"))
+	assert.Equal(t, ErrCloseSent, conn.WriteMessage(TextMessage, []byte("late")))
+	// the server echoes the code.
+	_, _, err = conn.ReadMessage()
+	assert.True(t, IsCloseError(err, CloseGoingAway), "%v", err)
+	assert.False(t, IsCloseError(err, CloseNormalClosure))
+	assert.Equal(t, err, conn.readErr)
+}
+
+func TestHandshakeErrors(t *testing.T) {
+	var upgradeErr error
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, upgradeErr = (&Upgrader{}).Upgrade(w, r, nil)
+	}))
+	defer srv.Close()
+
+	valid := func() *http.Request {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		req.Header.Set("Connection", "keep-alive, Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		return req
+	}
+	tests := []struct {
+		name   string
+		modify func(r *http.Request)
+		status int
+	}{
+		{"method", func(r *http.Request) { r.Method = http.MethodPost }, http.StatusMethodNotAllowed},
+		{"connection", func(r *http.Request) { r.Header.Set("Connection", "keep-alive") }, http.StatusBadRequest},
+		{"upgrade", func(r *http.Request) { r.Header.Del("Upgrade") }, http.StatusBadRequest},
+		{"version", func(r *http.Request) { r.Header.Set("Sec-WebSocket-Version", "8") }, http.StatusUpgradeRequired},
+		{"key", func(r *http.Request) { r.Header.Set("Sec-WebSocket-Key", "short") }, http.StatusBadRequest},
+		{"origin", func(r *http.Request) { r.Header.Set("Origin", "http://evil.example") }, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := valid()
+		tt.modify(req)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err, tt.name)
+		resp.Body.Close()
+		assert.Equal(t, tt.status, resp.StatusCode, tt.name)
+		if assert.IsType(t, &HandshakeError{}, upgradeErr, tt.name) {
+			assert.Equal(t, tt.status, upgradeErr.(*HandshakeError).Status, tt.name)
+		}
+	}
+
+	req := valid()
+	req.Header.Set("Sec-WebSocket-Version", "8")
+	resp, _ := http.DefaultClient.Do(req)
+	resp.Body.Close()
+	assert.Equal(t, "13", resp.Header.Get("Sec-WebSocket-Version"))
+}
+
+// failingHijacker is a ResponseWriter whose connection can not be hijacked.
+type failingHijacker struct{ *httptest.ResponseRecorder }
+
+func (failingHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, http.ErrHijacked
+}
+
+func TestHijackError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+
+	w := failingHijacker{httptest.NewRecorder()}
+	_, err := (&Upgrader{}).Upgrade(w, req, nil)
+	assert.EqualError(t, err, "websocket: hijack failed: http: connection has been hijacked")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestAcceptKey(t *testing.T) {
+	// the example of RFC 6455, section 1.3.
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", acceptKey("dGhlIHNhbXBsZSBub25jZQ=="))
+}
+
+// rawFrames sends the frames to a server side Conn and returns the error of
+// ReadMessage with the frames written back by the Conn.
+func rawFrames(t *testing.T, frames ...[]byte) (error, []byte) {
+	client, server := net.Pipe()
+	c := newConn(server, nil, true)
+	c.SetReadLimit(16)
+
+	replies := make(chan []byte)
+	go func() {
+		b, _ := io.ReadAll(client)
+		replies <- b
+	}()
+	go func() {
+		for _, f := range frames {
+			client.Write(f)
+		}
+	}()
+	_, _, err := c.ReadMessage()
+	server.Close()
+	return err, <-replies
+}
+
+// maskedFrame builds a client frame with a zero masking key.
+func maskedFrame(b0 byte, payload string) []byte {
+	return append([]byte{b0, maskBit | byte(len(payload)), 0, 0, 0, 0}, payload...)
+}
+
+func TestHugeFrameLength(t *testing.T) {
+	client, server := net.Pipe()
+	c := newConn(server, nil, true)
+	c.SetReadLimit(0)
+	go func() {
+		// a frame announcing 2^62 bytes, followed by a few of them.
+		client.Write([]byte{finalBit | BinaryMessage, maskBit | 127, 0x40, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+		client.Write([]byte("abc"))
+		client.Close()
+	}()
+	_, _, err := c.ReadMessage()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	server.Close()
+
+	// the limit check does not overflow with the fragments already read.
+	err, _ = rawFrames(t,
+		maskedFrame(TextMessage, "ab"),
+		[]byte{finalBit | continuationFrame, maskBit | 127, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0})
+	assert.Equal(t, ErrReadLimit, err)
+}
+
+func TestProtocolErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames [][]byte
+		code   int
+	}{
+		{"unmasked", [][]byte{{finalBit | TextMessage, 1, 'a'}}, CloseProtocolError},
+		{"reserved bits", [][]byte{maskedFrame(finalBit|0x40|TextMessage, "a")}, CloseProtocolError},
+		{"unknown opcode", [][]byte{maskedFrame(finalBit|3, "a")}, CloseProtocolError},
+		{"continuation first", [][]byte{maskedFrame(finalBit, "a")}, CloseProtocolError},
+		{"data inside fragments", [][]byte{maskedFrame(TextMessage, "a"), maskedFrame(finalBit|TextMessage, "b")}, CloseProtocolError},
+		{"fragmented ping", [][]byte{maskedFrame(PingMessage, "a")}, CloseProtocolError},
+		{"bad close code", [][]byte{maskedFrame(finalBit|CloseMessage, "\x03\xed")}, CloseProtocolError},
+		{"invalid utf8", [][]byte{maskedFrame(finalBit|TextMessage, "\xff")}, CloseInvalidFramePayloadData},
+		{"too big", [][]byte{maskedFrame(finalBit|BinaryMessage, strings.Repeat("x", 17))}, CloseMessageTooBig},
+	}
+	for _, tt := range tests {
+		err, reply := rawFrames(t, tt.frames...)
+		assert.Error(t, err, tt.name)
+		// a close frame sent by the server, with the code and a reason.
+		if assert.True(t, len(reply) >= 4, tt.name) {
+			assert.Equal(t, byte(finalBit|CloseMessage), reply[0], tt.name)
+			assert.Equal(t, FormatCloseMessage(tt.code, ""), reply[2:4], tt.name)
+		}
+	}
+}
+
+func TestControlFramesBetweenFragments(t *testing.T) {
+	err, reply := rawFrames(t,
+		maskedFrame(TextMessage, "he"),
+		maskedFrame(finalBit|PingMessage, "p"),
+		maskedFrame(finalBit, "llo"),
+		maskedFrame(finalBit|CloseMessage, ""),
+	)
+	// the ping is answered, the message is returned before the close frame
+	// is read, so ReadMessage has no error here.
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{finalBit | PongMessage, 1, 'p'}, reply)
+}
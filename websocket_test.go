@@ -0,0 +1,72 @@
+package gweb
+
+import (
+	"github.com/chen-zyc/gweb/websocket"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouterGroupWebSocket(t *testing.T) {
+	userKey := NewKey[string]("user")
+	statuses := make(chan int, 1)
+	s := NewServer()
+	s.Global(func(c *Context) {
+		c.Next()
+		if c.FullPath() == "/api/chat/:room" {
+			statuses <- c.Writer().Status()
+		}
+	})
+	api := s.Group("/api", func(c *Context) {
+		if c.Query("token") != "secret" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		Set(c, userKey, "gopher")
+	})
+	api.WebSocket("/chat/:room", func(c *Context) {
+		conn := c.WebSocket()
+		for {
+			_, p, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			user, _ := Get(c, userKey)
+			reply := c.Param("room") + "/" + user + ": " + string(p)
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(reply)); err != nil {
+				return
+			}
+		}
+	})
+	s.GET("/plain", func(c *Context) {
+		assert.Nil(t, c.WebSocket())
+	})
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	conn, _, err := websocket.Dial(url+"/api/chat/go?token=secret", nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+	assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("hi")))
+	_, p, err := conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, "go/gopher: hi", string(p))
+	conn.Close()
+	// the upgrade is reported as 101 once the handlers return.
+	assert.Equal(t, http.StatusSwitchingProtocols, <-statuses)
+
+	// the middleware rejects the request before the upgrade.
+	_, resp, err := websocket.Dial(url+"/api/chat/go", nil)
+	assert.Equal(t, websocket.ErrBadHandshake, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, <-statuses)
+
+	// not an upgrade request.
+	w := performRequest(s, MethodGet, "/api/chat/go?token=secret")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, http.StatusBadRequest, <-statuses)
+	performRequest(s, MethodGet, "/plain")
+}